@@ -30,7 +30,7 @@
 var branchCmd = &cobra.Command{
 	Use:     "branch",
 	Short:   "List all branches",
-	Long:    `List all branches of the repository`,
+	Long:    `List all branches of the repository with their upstream, the commits to push (↑) and to pull (↓), their last commit and whether they have been merged into the default branch`,
 	Run:     controller.Branch,
 	Aliases: []string{"b", "br", "branch ls", "branch list", "b ls", "br ls", "branches"},
 }
@@ -38,7 +38,7 @@
 var branchAddCmd = &cobra.Command{
 	Use:     "add",
 	Short:   "Alias of gut switch",
-	Run:     controller.Switch,
+	Run:     controller.Journaled(controller.Switch),
 	Args:    cobra.MaximumNArgs(1),
 	Aliases: []string{"new"},
 }
@@ -47,14 +47,52 @@
 	Use:     "delete",
 	Short:   "Delete a branch",
 	Long:    `Delete a branch from the repository`,
-	Run:     controller.BranchDelete,
+	Run:     controller.Journaled(controller.BranchDelete),
 	Args:    cobra.MaximumNArgs(1),
 	Aliases: []string{"del", "remove", "rm"},
 }
 
+var branchRenameCmd = &cobra.Command{
+	Use:   "rename [old] <new>",
+	Short: "Rename a branch",
+	Long: `Rename a branch. Without old, the current branch is renamed.
+The upstream is kept. If the branch has been pushed, gut can also rename the remote branch`,
+	Run:     controller.Journaled(controller.BranchRename),
+	Args:    cobra.MaximumNArgs(2),
+	Aliases: []string{"mv", "move"},
+}
+
+var branchConventionCmd = &cobra.Command{
+	Use:   "convention",
+	Short: "Set the naming convention of the branches",
+	Long: `Set how new branches must be named (e.g. {type}/{ticket}-{slug}) and which branch they start from.
+The convention is stored in the .gut file and enforced by gut switch.
+When a name doesn't follow it, gut asks for the type, the ticket id and a description of the branch to build one`,
+	Run:     controller.BranchConvention,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"naming"},
+}
+
+var branchPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete merged, stale and deleted branches",
+	Long: `List the branches merged into the default branch, the branches without commit for a while,
+and the branches whose remote branch has been deleted. Then delete the ones you select, locally and optionally on the remote.
+The remotes are fetched first to detect the deleted branches`,
+	Run:     controller.Journaled(controller.BranchPrune),
+	Args:    cobra.NoArgs,
+	Aliases: []string{"clean", "cleanup"},
+}
+
 func init() {
 	rootCmd.AddCommand(branchCmd)
 	branchCmd.AddCommand(branchAddCmd)
 	branchCmd.AddCommand(branchDeleteCmd)
+	branchCmd.AddCommand(branchRenameCmd)
+	branchRenameCmd.Flags().BoolP("remote", "r", false, "Also rename the remote branch without asking")
+	branchCmd.AddCommand(branchPruneCmd)
+	branchCmd.AddCommand(branchConventionCmd)
+	branchPruneCmd.Flags().IntP("days", "d", 90, "Number of days without commit after which a branch is stale (0 to disable)")
+	branchPruneCmd.Flags().Bool("no-fetch", false, "Don't fetch the remotes before looking for deleted branches")
 
 }
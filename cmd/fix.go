@@ -32,9 +32,13 @@
 	Short: "Fix your mess",
 	Long: `A command to fix your mess. Available fixes:
 	1) Change last commit message
-	2) Commit on the wrong branch`,
+	2) Commit on the wrong branch
+	3) Discard changes since last commit (with gut reset)
+	4) Go back to a previous commit (with gut revert)
+	5) Forgot a change in the last commit
+	6) Too much in one commit (split it into several commits)`,
 	Aliases: []string{"fixes", "bobthebuilder", "bob"},
-	Run:     controller.Fix,
+	Run:     controller.Journaled(controller.Fix),
 }
 
 func init() {
@@ -30,7 +30,7 @@
 var gotoCmd = &cobra.Command{
 	Use:     "goto [full commit hash]",
 	Short:   "Switch temporarily your working tree to an old commit",
-	Run:     controller.Goto,
+	Run:     controller.Journaled(controller.Goto),
 	Aliases: []string{"look", "lookout", "visualize"},
 }
 
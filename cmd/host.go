@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// hostCmd represents the host command
+var hostCmd = &cobra.Command{
+	Use:   "host",
+	Short: "List the self-hosted websites gut knows",
+	Long: `List the self-hosted websites (GitHub Enterprise, GitLab, Gitea, Forgejo...) and the API gut uses for them.
+GitHub, GitLab, Bitbucket, Codeberg and Gitea.com are known without config.
+The hosts are stored in ~/.gut/hosts.toml`,
+	Run:     controller.Host,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"hosts"},
+}
+
+var hostListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the self-hosted websites gut knows",
+	Run:     controller.Host,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"ls"},
+}
+
+var hostAddCmd = &cobra.Command{
+	Use:   "add [host]",
+	Short: "Tell gut which software runs on a self-hosted website",
+	Long: `Map a host (e.g. git.company.com) to a provider (github, gitlab, bitbucket, gitea or forgejo) and the base URL of its API.
+gut then opens pull requests and compare pages on it like on the public websites.
+If the host is already configured, it is replaced`,
+	Run:  controller.HostAdd,
+	Args: cobra.MaximumNArgs(1),
+}
+
+var hostRemoveCmd = &cobra.Command{
+	Use:     "remove [host]",
+	Short:   "Forget a self-hosted website",
+	Run:     controller.HostRemove,
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"rm", "delete", "del"},
+}
+
+func init() {
+	rootCmd.AddCommand(hostCmd)
+	hostCmd.AddCommand(hostListCmd)
+	hostCmd.AddCommand(hostAddCmd)
+	hostCmd.AddCommand(hostRemoveCmd)
+	hostAddCmd.Flags().StringP("provider", "p", "", "Software running on the host: github, gitlab, bitbucket, gitea or forgejo")
+	hostAddCmd.Flags().String("api", "", "Base URL of the API (default: the usual URL of the provider on the host)")
+}
@@ -32,10 +32,12 @@
 	Use:   "merge [branch]",
 	Short: "Merge a branch into the current one",
 	Long: `Merge a branch into the current one
-If you use GitHub, GitLab or Bitbucket, it will open a page to create a pull request`,
-	Run:     controller.Merge,
+If you use GitHub, GitLab or Bitbucket, it will open a page to create a pull request.
+Otherwise, the branch is merged locally (requires git): the incoming commits and the files with conflicts are shown first.
+If there are conflicts, gut guides you through them. You can abort the merge at any time to go back to where you were`,
+	Run:     controller.Journaled(controller.Merge),
 	Args:    cobra.MaximumNArgs(1),
-	Aliases: []string{"mg", "mrg", "pr", "ppap"}, // Stands for Pen Pineapple Apple Pen
+	Aliases: []string{"mg", "mrg", "ppap"}, // Stands for Pen Pineapple Apple Pen
 }
 
 func init() {
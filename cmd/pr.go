@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// prCmd represents the pr command
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Merge a branch into the current one, or manage pull requests",
+	Long: `Without subcommand, same as gut merge.
+Use the subcommands to work with the pull requests of GitHub, GitLab, Bitbucket and Gitea from the terminal`,
+	Run:     controller.Journaled(controller.Merge),
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"pull-request", "mr"},
+}
+
+var prCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Open a pull request for the current branch",
+	Long: `Open a pull request for the current branch using the API of the website hosting the repository, with the token of your profile.
+Self-hosted websites must be added first with gut host add
+The title and the description are written from the commits of the branch. The branch is pushed first if needed.
+The link to the pull request is printed, so it works without a browser`,
+	Run:     controller.PullRequestCreate,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"new", "open"},
+}
+
+var prListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the open pull requests of the repository",
+	Long:    "List the open pull requests of the repository with their author and branches, using the API of the website hosting it",
+	Run:     controller.PullRequestList,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"ls"},
+}
+
+var prCheckoutCmd = &cobra.Command{
+	Use:   "checkout <number>",
+	Short: "Switch to the branch of a pull request to review it",
+	Long: `Fetch the commits of a pull request into a local branch and switch to it.
+The branch has the name of the branch of the pull request, or pr/<number> if it comes from a fork.
+If the branch already exists, it's updated with the new commits of the pull request`,
+	Run:     controller.Journaled(controller.PullRequestCheckout),
+	Args:    cobra.ExactArgs(1),
+	Aliases: []string{"co", "review"},
+}
+
+var prStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the pull request of the current branch",
+	Long:  "Show the pull request of the current branch: whether it's approved and whether it can be merged",
+	Run:   controller.PullRequestStatus,
+	Args:  cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+	prCmd.AddCommand(prCreateCmd)
+	prCmd.AddCommand(prListCmd)
+	prCmd.AddCommand(prCheckoutCmd)
+	prCmd.AddCommand(prStatusCmd)
+	prCreateCmd.Flags().StringP("title", "t", "", "Title of the pull request")
+	prCreateCmd.Flags().StringP("body", "b", "", "Description of the pull request")
+	prCreateCmd.Flags().String("base", "", "Branch to merge into (default: the default branch)")
+	prCreateCmd.Flags().StringSliceP("reviewer", "r", nil, "Username of a reviewer (can be repeated)")
+	prCreateCmd.Flags().StringSliceP("label", "l", nil, "Label to add (can be repeated)")
+	prCreateCmd.Flags().BoolP("draft", "d", false, "Open the pull request as a draft")
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// protectCmd represents the protect command
+var protectCmd = &cobra.Command{
+	Use:   "protect",
+	Short: "List the protected branches",
+	Long: `List the rules protecting the branches of the repository.
+Rules are stored in the .gut file, which isn't committed: they only apply to your copy of the repository.
+A protected branch can forbid saving directly on it, rewriting its history, deleting it or pushing to it.
+Run a command with --override-protection to bypass a rule. The override is recorded in the commit message`,
+	Run:     controller.Protect,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"protection", "protected"},
+}
+
+var protectListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the protected branches",
+	Run:     controller.Protect,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"ls"},
+}
+
+var protectAddCmd = &cobra.Command{
+	Use:   "add [pattern]",
+	Short: "Protect a branch",
+	Long: `Protect the branches matching a pattern (e.g. main or release/*).
+If a rule already exists for the pattern, it is replaced`,
+	Run:  controller.ProtectAdd,
+	Args: cobra.MaximumNArgs(1),
+}
+
+var protectRemoveCmd = &cobra.Command{
+	Use:     "remove [pattern]",
+	Short:   "Remove a protection rule",
+	Run:     controller.ProtectRemove,
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"rm", "delete", "del"},
+}
+
+func init() {
+	rootCmd.AddCommand(protectCmd)
+	protectCmd.AddCommand(protectListCmd)
+	protectCmd.AddCommand(protectAddCmd)
+	protectCmd.AddCommand(protectRemoveCmd)
+}
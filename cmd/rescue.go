@@ -0,0 +1,42 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// rescueCmd represents the rescue command
+var rescueCmd = &cobra.Command{
+	Use:   "rescue",
+	Short: "Find the commits lost after an undo, a squash, a goto... and bring them back",
+	Long: `Look for the commits no branch leads to anymore, in the reflogs and in the objects of the repository.
+The commits lost recently are listed with their title, their date and the operation that lost them.
+A commit can be restored as a new branch, or the current branch can be reset to it.`,
+	Aliases: []string{"recover", "lost"},
+	Run:     controller.Journaled(controller.Rescue),
+}
+
+func init() {
+	rootCmd.AddCommand(rescueCmd)
+}
@@ -32,8 +32,11 @@
 	Short: "Rollback files specified to the last commit (require git)",
 	Long: `Rollback the repository to the last commit. If you have uncommitted changes, they will be lost.
 If zero arguments are passed, all the files will be rolled back.
-If one or more arguments are passed, only the files passed as arguments will be rolled back.`,
-	Run: controller.Undo,
+If one or more arguments are passed, only the files passed as arguments will be rolled back.
+
+Before discarding them, gut saves your changes (untracked files included) in a snapshot and prints how to get them back.
+Snapshots are kept 14 days. Change it with retention_days in the [snapshot] section of the .gut file (-1 keeps them forever).`,
+	Run: controller.Journaled(controller.Undo),
 }
 
 func init() {
@@ -31,7 +31,7 @@
 	Use:     "revert",
 	Short:   "Revert to a specified commit (require Git)",
 	Aliases: []string{"goback", "rollback"},
-	Run:     controller.Revert,
+	Run:     controller.Journaled(controller.Revert),
 }
 
 func init() {
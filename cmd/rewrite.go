@@ -0,0 +1,49 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// rewriteCmd represents the rewrite command
+var rewriteCmd = &cobra.Command{
+	Use:   "rewrite",
+	Short: "Reorder, reword, drop, squash or edit the commits that aren't pushed yet",
+	Long: `Rewrite the history of the commits that aren't pushed yet.
+For each commit, choose to keep it, reword its message, drop it, squash it into the previous commit, or pause on it to change its content.
+Commits can also be moved up and down. The history after the rewrite is shown before anything is changed.
+
+If a commit doesn't apply cleanly or the rewrite is paused, run gut rewrite --continue once you're done, or gut rewrite --abort to restore the branch.`,
+	Example: `  gut rewrite
+  gut rewrite --continue
+  gut rewrite --abort`,
+	Aliases: []string{"rebase", "reword"},
+	Run:     controller.Journaled(controller.Rewrite),
+}
+
+func init() {
+	rootCmd.AddCommand(rewriteCmd)
+	rewriteCmd.Flags().Bool("continue", false, "Continue the rewrite in progress")
+	rewriteCmd.Flags().Bool("abort", false, "Abort the rewrite in progress and restore the branch")
+}
@@ -35,6 +35,10 @@
 	Gut is a powerful command-line interface (CLI) designed to make Git easier to use.
 	Effortlessly version control your projects with Gut.`,
 	Run: controller.Root,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		override, _ := cmd.Flags().GetBool("override-protection")
+		controller.SetProtectionOverride(override)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -52,5 +56,6 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().BoolP("version", "v", false, "Print the version of Gut and the release date")
+	rootCmd.PersistentFlags().Bool("override-protection", false, "Allow operations forbidden by the protected branches rules of .gut")
 
 }
@@ -29,7 +29,7 @@
 
 // saveCmd represents the save command
 var saveCmd = &cobra.Command{
-	Use:   "save [-e=[editor]] [-m=message] [-t=title] [files...]",
+	Use:   "save [-e=[editor]] [-m=message] [-t=title] [--fixup [commit]] [files...]",
 	Short: "Save (commit) your current work locally",
 	Long: `Save (commit) your current work locally
 To commit only some files, pass them as arguments to the command.
@@ -37,9 +37,12 @@
 
 The -e flag allows you to specify the editor to use for writing the commit message.
 If you want to use the default Git editor, you can also use the -e flag without any argument (e.g., git save -e).
-To specify the editor, use the -e flag followed by the editor command (e.g. gut save -e="mate -w")`,
+To specify the editor, use the -e flag followed by the editor command (e.g. gut save -e="mate -w")
+
+The --fixup flag saves the changes as a fix of a commit that isn't pushed yet (e.g. gut save --fixup 3b4c5d6).
+Without a commit, you choose it from a list. Run gut squash --auto to squash the fixes into their commits.`,
 	Aliases: []string{"s", "commit"},
-	Run:     controller.Save,
+	Run:     controller.Journaled(controller.Save),
 }
 
 func init() {
@@ -55,4 +58,8 @@ func init() {
 	saveCmd.Flags().StringP("editor", "e", "none", "The editor to use to write the commit message. Set -e to use the default git editor or specify one with -e=\"editor\"")
 	saveCmd.Flag("editor").NoOptDefVal = "config"
 
+	// --fixup without a commit opens a picker
+	saveCmd.Flags().String("fixup", "", "Save the changes as a fix of a commit, to squash later with gut squash --auto")
+	saveCmd.Flag("fixup").NoOptDefVal = "choose"
+
 }
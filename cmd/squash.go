@@ -28,12 +28,18 @@
 
 // squashCmd represents the squash command
 var squashCmd = &cobra.Command{
-	Use:     "squash [commit]",
-	Short:   "Squash your commits from HEAD to a specific commit",
-	Example: `  gut squash 3b4c5d6`,
-	Run:     controller.Squash,
+	Use:   "squash [commit]",
+	Short: "Squash your commits from HEAD to a specific commit",
+	Long: `Squash your commits from HEAD to a specific commit
+
+With --auto, the fixup commits made with gut save --fixup are squashed into the commits they fix.
+The other commits keep their order.`,
+	Example: `  gut squash 3b4c5d6
+  gut squash --auto`,
+	Run: controller.Journaled(controller.Squash),
 }
 
 func init() {
 	rootCmd.AddCommand(squashCmd)
+	squashCmd.Flags().BoolP("auto", "a", false, "Squash the fixup commits into the commits they fix")
 }
@@ -0,0 +1,105 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// stashCmd represents the stash command
+var stashCmd = &cobra.Command{
+	Use:   "stash [message]",
+	Short: "Put your uncommitted changes aside",
+	Long: `Save your uncommitted changes in the stash with a message and clean the working tree.
+New files are only stashed with --untracked.
+Use the subcommands to list the entries of the stash and get them back`,
+	Run: controller.Journaled(controller.Stash),
+}
+
+var stashListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the entries of the stash",
+	Long:    `List the entries of the stash with the branch they were stashed on, their age and the number of files changed`,
+	Run:     controller.StashList,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"ls"},
+}
+
+var stashShowCmd = &cobra.Command{
+	Use:     "show [entry]",
+	Short:   "Show the changes of a stash entry",
+	Run:     controller.StashShow,
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"diff"},
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply [entry]",
+	Short: "Apply a stash entry and keep it in the stash",
+	Run:   controller.Journaled(controller.StashApply),
+	Args:  cobra.MaximumNArgs(1),
+}
+
+var stashPopCmd = &cobra.Command{
+	Use:     "pop [entry]",
+	Short:   "Apply a stash entry and remove it from the stash",
+	Run:     controller.Journaled(controller.StashPop),
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"restore"},
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:     "drop [entry]",
+	Short:   "Remove a stash entry",
+	Run:     controller.Journaled(controller.StashDrop),
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"rm", "delete", "del"},
+}
+
+var stashClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all the entries of the stash",
+	Run:   controller.Journaled(controller.StashClear),
+	Args:  cobra.NoArgs,
+}
+
+var stashBranchCmd = &cobra.Command{
+	Use:   "branch [name] [entry]",
+	Short: "Create a branch from a stash entry",
+	Long: `Create a branch from the commit a stash entry was stashed on, switch to it and apply the entry.
+The entry is removed from the stash if it applies without conflict`,
+	Run:  controller.Journaled(controller.StashBranch),
+	Args: cobra.MaximumNArgs(2),
+}
+
+func init() {
+	rootCmd.AddCommand(stashCmd)
+	stashCmd.Flags().BoolP("untracked", "u", false, "Also stash the new files")
+	stashCmd.AddCommand(stashListCmd)
+	stashCmd.AddCommand(stashShowCmd)
+	stashCmd.AddCommand(stashApplyCmd)
+	stashCmd.AddCommand(stashPopCmd)
+	stashCmd.AddCommand(stashDropCmd)
+	stashCmd.AddCommand(stashClearCmd)
+	stashCmd.AddCommand(stashBranchCmd)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 Julien CAGNIART
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/julien040/gut/src/controller"
+	"github.com/spf13/cobra"
+)
+
+// submoduleCmd represents the submodule command
+var submoduleCmd = &cobra.Command{
+	Use:   "submodule",
+	Short: "Show the status of the submodules",
+	Long: `Show the submodules of the repository and whether the commit checked out in each one matches the commit recorded.
+Submodules are repositories included in your repository. They are listed in the .gitmodules file`,
+	Run:     controller.SubmoduleStatus,
+	Aliases: []string{"submodules", "sub"},
+}
+
+var submoduleStatusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show the status of the submodules",
+	Run:     controller.SubmoduleStatus,
+	Aliases: []string{"st", "ls", "list"},
+}
+
+var submoduleAddCmd = &cobra.Command{
+	Use:   "add <url> [path]",
+	Short: "Add a repository as a submodule",
+	Long: `Clone a repository in your working tree and register it as a submodule.
+By default, it is cloned in a folder named after the repository`,
+	Run:  controller.Journaled(controller.SubmoduleAdd),
+	Args: cobra.MaximumNArgs(2),
+}
+
+var submoduleUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Download the submodules and check out the commits recorded",
+	Long: `Download the submodules that are missing and check out the commit recorded in the repository for each one.
+Submodules with uncommitted changes are left untouched`,
+	Run:     controller.Journaled(controller.SubmoduleUpdate),
+	Args:    cobra.NoArgs,
+	Aliases: []string{"up", "init"},
+}
+
+var submoduleRemoveCmd = &cobra.Command{
+	Use:     "remove [path]",
+	Short:   "Remove a submodule",
+	Long:    `Unregister a submodule and delete its files`,
+	Run:     controller.Journaled(controller.SubmoduleRemove),
+	Args:    cobra.MaximumNArgs(1),
+	Aliases: []string{"rm", "delete", "del"},
+}
+
+func init() {
+	rootCmd.AddCommand(submoduleCmd)
+	submoduleCmd.AddCommand(submoduleStatusCmd)
+	submoduleCmd.AddCommand(submoduleAddCmd)
+	submoduleCmd.AddCommand(submoduleUpdateCmd)
+	submoduleCmd.AddCommand(submoduleRemoveCmd)
+}
@@ -28,12 +28,19 @@
 
 // switchCmd represents the switch command
 var switchCmd = &cobra.Command{
-	Use:   "switch",
+	Use:   "switch [branch | commit | -]",
 	Short: "Change the current branch",
-	Run:   controller.Switch,
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Switch to a branch or a commit.
+Without argument, pick a branch from the most recently used. Type to filter them.
+Use gut switch - to go back to the previous branch.
+Uncommitted changes can be kept on the branch you leave: gut restores them when you come back.
+If the branch only exists on a remote (e.g. origin/feature), a local branch tracking it is created.
+Use --fetch to fetch the remotes first and find the branches pushed since your last sync`,
+	Run:  controller.Journaled(controller.Switch),
+	Args: cobra.MaximumNArgs(1),
 }
 
 func init() {
 	rootCmd.AddCommand(switchCmd)
+	switchCmd.Flags().BoolP("fetch", "f", false, "Fetch the remotes before looking for the branch")
 }
@@ -30,12 +30,27 @@
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync your local changes with the remote repository",
-	Long:  `Execute a git pull and a git push to sync your local changes with the remote repository`,
-	Run:   controller.Sync,
+	Long: `Execute a git pull and a git push to sync your local changes with the remote repository
+The current branch is synced with the remote branch it tracks. On the first sync, the branch is published and tracked.
+
+Use --all to sync every branch that tracks a remote branch`,
+	Run: controller.Journaled(controller.Sync),
+}
+
+var syncSetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Choose the remotes to sync with (mirrors)",
+	Long: `Choose the remote gut sync pulls from and the remotes it pushes to, each with its own profile.
+Useful when the repository is mirrored on several platforms (e.g. GitHub and a self-hosted Gitea).
+The settings are saved in the .gut file`,
+	Aliases: []string{"mirror", "mirrors", "targets"},
+	Run:     controller.SyncSetup,
 }
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncSetupCmd)
+	syncCmd.Flags().BoolP("all", "a", false, "Sync every branch that tracks a remote branch")
 
 	// Here you will define your flags and configuration settings.
 
@@ -30,18 +30,27 @@
 
 // undoCmd represents the undo command
 var undoCmd = &cobra.Command{
-	Use:   "undo [file]...",
-	Short: "Rollback files specified to the last commit (require git)",
-	Long: `Rollback the repository to the last commit. If you have uncommitted changes, they will be lost.
-If zero arguments are passed, all the files will be rolled back.
-If one or more arguments are passed, only the files passed as arguments will be rolled back.`,
+	Use:   "undo [--last]",
+	Short: "Undo an operation of gut (save, squash, fix, switch, revert, merge, sync...)",
+	Long: `Put the refs, HEAD and the staged files back as they were before an operation of gut.
+Without argument, pick the operation to undo from the journal of gut. Use --last to undo the last one.
+Undoing an operation also undoes the ones made after it. The undo itself can be undone.
+The stash is never undone, so that none of its entries is lost. Protected branches are only deleted or rewritten with --override-protection.
+
+Passing files to gut undo is deprecated: use gut reset to roll them back to the last commit.`,
+	Example: `  gut undo
+  gut undo --last`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Starting from v.1.0.0, the undo command is deprecated. Please use the reset command instead.")
-		controller.Undo(cmd, args)
+		if len(args) > 0 {
+			fmt.Println("Starting from v.1.0.0, rolling back files with the undo command is deprecated. Please use the reset command instead.")
+			controller.Undo(cmd, args)
+			return
+		}
+		controller.Journaled(controller.UndoOperation)(cmd, args)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(undoCmd)
-
+	undoCmd.Flags().BoolP("last", "l", false, "Undo the last operation without asking which one")
 }
@@ -1,10 +1,20 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5"
 
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
 	"github.com/julien040/gut/src/prompt"
 	"github.com/spf13/cobra"
 )
@@ -30,16 +40,64 @@ func Branch(cmd *cobra.Command, args []string) {
 		exitOnError("I can't get the current branch", err)
 	}
 
+	defaultBranch, err := executor.GetDefaultBranch(wd)
+	if err != nil {
+		exitOnError("I can't find the default branch", err)
+	}
+
+	infos := make([]executor.BranchInfo, len(branches))
+	width := 0
+	for i, branch := range branches {
+		infos[i], err = executor.GetBranchInfo(wd, branch, defaultBranch)
+		if err != nil {
+			exitOnError("I can't get the details of the branch "+branch, err)
+		}
+		width = max(width, len(branch))
+	}
+
 	// Print the branches
 	print.Message("Here is the list of all branches:", "none")
-	for _, branch := range branches {
-		if branch == currentBranch {
-			print.Message("* "+branch, "green")
-		} else {
-			print.Message("  "+branch, "none")
-		}
+	now := time.Now()
+	for _, info := range infos {
+		fmt.Fprintln(color.Output, formatBranchInfo(info, info.Name == currentBranch, defaultBranch, width, now))
+	}
+
+}
+
+// Format a branch on one line with its upstream, its last commit and whether it has been merged
+//
+// e.g. * main  → origin/main ↑1  Fix the login · Julien · 3 days ago
+func formatBranchInfo(info executor.BranchInfo, current bool, defaultBranch string, width int, now time.Time) string {
+	name := fmt.Sprintf("%-*s", width, info.Name)
+	var line string
+	if current {
+		line = color.GreenString("* " + name)
+	} else {
+		line = "  " + name
+	}
+
+	if info.Upstream.Remote != "" {
+		line += color.HiBlackString(" → " + info.Upstream.String())
+	}
+	if info.UpstreamGone {
+		line += color.RedString(" (gone)")
+	}
+	if info.Ahead > 0 {
+		line += color.YellowString(" ↑%d", info.Ahead)
+	}
+	if info.Behind > 0 {
+		line += color.YellowString(" ↓%d", info.Behind)
+	}
+	if info.Merged {
+		line += color.CyanString(" merged into " + defaultBranch)
 	}
 
+	title := []rune(getTitleFromCommit(info.LastCommit.Message))
+	if len(title) > 50 {
+		title = append(title[:47], []rune("...")...)
+	}
+	line += color.HiBlackString("  %s · %s · %s", string(title), info.LastCommit.Author.Name, formatAge(info.LastCommit.Author.When, now))
+	return line
 }
 
 func BranchDelete(cmd *cobra.Command, args []string) {
@@ -100,6 +158,7 @@ func BranchDelete(cmd *cobra.Command, args []string) {
 		print.Message("You can't delete the current branch. I recommend you to switch to another branch first", print.Error)
 		return
 	}
+	checkBranchProtection(wd, branchName, actionDelete)
 
 	res, err := prompt.InputBool("Are you sure you want to delete the local branch "+branchName+"?", false)
 	if err != nil {
@@ -142,3 +201,298 @@ func BranchDelete(cmd *cobra.Command, args []string) {
 
 	print.Message("The branch has been deleted", print.Success)
 }
+
+func BranchRename(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+
+	currentBranch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("I can't get the current branch", err)
+	}
+
+	// gut branch rename <new> renames the current branch
+	var oldName, newName string
+	switch len(args) {
+	case 2:
+		oldName, newName = args[0], args[1]
+	case 1:
+		oldName, newName = currentBranch, args[0]
+	default:
+		branches, err := executor.ListBranches(wd)
+		if err != nil {
+			exitOnError("I can't list the branches", err)
+		}
+		oldName, err = prompt.InputSelect("Which branch do you want to rename?", branches)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		newName, err = prompt.InputLine("New name: ")
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+
+	if oldName == "HEAD" {
+		print.Message("You are not on a branch. Tell me which branch to rename: gut branch rename <old> <new>", print.Error)
+		os.Exit(1)
+	}
+	exists, err := executor.CheckIfBranchExists(wd, oldName)
+	if err != nil {
+		exitOnError("I can't check if the branch exists", err)
+	}
+	if !exists {
+		print.Message("The branch "+oldName+" doesn't exist. I can't rename it", print.Error)
+		os.Exit(1)
+	}
+	if oldName == newName {
+		print.Message("The branch is already named "+newName, print.Success)
+		return
+	}
+	checkBranchProtection(wd, oldName, actionDelete)
+	if executor.ValidateBranchName(newName) != nil {
+		print.Message(newName+" isn't a valid branch name. Avoid spaces and special characters like ~ ^ : ? * [", print.Error)
+		os.Exit(1)
+	}
+	exists, err = executor.CheckIfBranchExists(wd, newName)
+	if err != nil {
+		exitOnError("I can't check if the branch exists", err)
+	}
+	if exists {
+		print.Message("The branch "+newName+" already exists. Choose another name or delete it first", print.Error)
+		os.Exit(1)
+	}
+
+	// Read before renaming because the config moves with the branch
+	upstream, err := executor.GetUpstream(wd, oldName)
+	if err != nil {
+		exitOnError("I can't get the upstream of the branch "+oldName, err)
+	}
+
+	err = executor.RenameBranch(wd, oldName, newName)
+	if err != nil {
+		exitOnError("I can't rename the branch", err)
+	}
+	print.Message(`I've renamed the branch "`+oldName+`" to "`+newName+`" 🎉`, print.Success)
+
+	if upstream.Remote == "" {
+		return
+	}
+	renameRemote, _ := cmd.Flags().GetBool("remote")
+	if !renameRemote {
+		renameRemote, err = prompt.InputBool("Do you also want to rename "+upstream.String()+" to "+upstream.Remote+"/"+newName+"?", false)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	if !renameRemote {
+		print.Message("Okay, "+newName+" still tracks "+upstream.String(), print.Info)
+		return
+	}
+	renameRemoteBranch(wd, upstream, newName, getRemoteProfile(wd, upstream.Remote))
+}
+
+// Push the renamed branch under its new name, track it and delete the old remote branch
+func renameRemoteBranch(path string, upstream executor.Upstream, newName string, profileLocal profile.Profile) {
+	err := runWithProgress("Pushing "+newName+" to "+upstream.Remote, func(ctx context.Context) error {
+		return executor.PushMirror(ctx, path, upstream.Remote, newName, profileLocal.Username, profileLocal.Password)
+	})
+	if isAuthError(err) {
+		print.Message("Uh oh, your credentials are wrong 😢. Please select another profile.", print.Error)
+		renameRemoteBranch(path, upstream, newName, getSyncProfile(path, true))
+		return
+	} else if err == git.ErrNonFastForwardUpdate {
+		print.Message("%s/%s already exists and has other commits. I won't overwrite it", print.Error, upstream.Remote, newName)
+		os.Exit(1)
+	} else if err != nil && err != git.NoErrAlreadyUpToDate {
+		exitOnError("I can't push the branch "+newName, err)
+	}
+
+	err = executor.SetUpstream(path, newName, upstream.Remote, newName)
+	if err != nil {
+		exitOnError("I can't set the upstream of the branch "+newName, err)
+	}
+
+	err = runWithProgress("Deleting "+upstream.String(), func(ctx context.Context) error {
+		return executor.DeleteRemoteBranch(ctx, path, upstream.Remote, upstream.Branch, profileLocal.Username, profileLocal.Password)
+	})
+	exitIfCancelled(err)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		print.Message("I've pushed %s/%s but I can't delete %s 😢: %s", print.Warning, upstream.Remote, newName, upstream.String(), err.Error())
+		return
+	}
+	print.Message("I've renamed %s to %s/%s 🎉", print.Success, upstream.String(), upstream.Remote, newName)
+}
+
+// A branch gut branch prune offers to delete
+type pruneCandidate struct {
+	Info   executor.BranchInfo
+	Reason string
+}
+
+// Delete the branches that are merged, stale or deleted on the remote
+func BranchPrune(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+
+	staleDays, _ := cmd.Flags().GetInt("days")
+	noFetch, _ := cmd.Flags().GetBool("no-fetch")
+	if !noFetch {
+		fetchAllRemotes(wd)
+	}
+
+	branches, err := executor.ListBranches(wd)
+	if err != nil {
+		exitOnError("I can't list the branches", err)
+	}
+	currentBranch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("I can't get the current branch", err)
+	}
+	defaultBranch, err := executor.GetDefaultBranch(wd)
+	if err != nil {
+		exitOnError("I can't find the default branch", err)
+	}
+
+	var candidates []pruneCandidate
+	now := time.Now()
+	for _, branch := range branches {
+		if branch == currentBranch || branch == defaultBranch {
+			continue
+		}
+		if _, protected := findProtectionRule(wd, branch, actionDelete); protected && !protectionOverridden {
+			continue
+		}
+		info, err := executor.GetBranchInfo(wd, branch, defaultBranch)
+		if err != nil {
+			exitOnError("I can't get the details of the branch "+branch, err)
+		}
+		switch {
+		case info.UpstreamGone:
+			candidates = append(candidates, pruneCandidate{Info: info, Reason: info.Upstream.String() + " has been deleted"})
+		case info.Merged:
+			candidates = append(candidates, pruneCandidate{Info: info, Reason: "merged into " + defaultBranch})
+		case staleDays > 0 && now.Sub(info.LastCommit.Author.When) > time.Duration(staleDays)*24*time.Hour:
+			candidates = append(candidates, pruneCandidate{Info: info, Reason: "last commit " + formatAge(info.LastCommit.Author.When, now)})
+		}
+	}
+	if len(candidates) == 0 {
+		print.Message("There is no merged, stale or deleted branch to clean up 🎉", print.Success)
+		return
+	}
+
+	width := 0
+	for _, candidate := range candidates {
+		width = max(width, len(candidate.Info.Name))
+	}
+	options := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		options[i] = fmt.Sprintf("%-*s  %s", width, candidate.Info.Name, candidate.Reason)
+	}
+	var selected []int
+	err = survey.AskOne(&survey.MultiSelect{
+		Message: "Which branches do you want to delete?",
+		Options: options,
+	}, &selected)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if len(selected) == 0 {
+		print.Message("Okay, I won't delete any branch", print.Info)
+		return
+	}
+
+	// Unmerged commits are only reachable from the branch
+	var unmerged []string
+	for _, i := range selected {
+		if !candidates[i].Info.Merged {
+			unmerged = append(unmerged, candidates[i].Info.Name)
+		}
+	}
+	if len(unmerged) > 0 {
+		res, err := prompt.InputBool("These branches have commits that are not in "+defaultBranch+": "+strings.Join(unmerged, ", ")+". Their commits will be lost. Do you want to continue?", false)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		if !res {
+			print.Message("Okay, I won't delete any branch", print.Info)
+			return
+		}
+	}
+
+	var onRemote []executor.Upstream
+	for _, i := range selected {
+		info := candidates[i].Info
+		err = executor.DeleteBranch(wd, info.Name)
+		if err != nil {
+			exitOnError("I can't delete the branch "+info.Name, err)
+		}
+		print.Message("Deleted %s", print.Success, info.Name)
+		if info.Upstream.Remote != "" && !info.UpstreamGone {
+			onRemote = append(onRemote, info.Upstream)
+		}
+	}
+
+	if len(onRemote) > 0 {
+		names := make([]string, len(onRemote))
+		for i, upstream := range onRemote {
+			names[i] = upstream.String()
+		}
+		res, err := prompt.InputBool("Do you also want to delete "+strings.Join(names, ", ")+" on the remote?", false)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		if res {
+			deleteRemoteBranches(wd, onRemote)
+		}
+	}
+	print.Message("I've deleted %d branch(es) 🎉", print.Success, len(selected))
+}
+
+// Fetch every remote with prune, e.g. to detect the branches created or deleted on the remotes
+//
+// A failed fetch (e.g. offline) only prints a warning
+func fetchAllRemotes(path string) {
+	remotes, err := executor.ListRemote(path)
+	if err != nil {
+		exitOnError("I can't list the remotes", err)
+	}
+	for _, remote := range remotes {
+		profileLocal := getRemoteProfile(path, remote.Name)
+		err := runWithProgress("Fetching "+remote.Name, func(ctx context.Context) error {
+			return executor.Fetch(ctx, path, remote.Name, true, profileLocal.Username, profileLocal.Password)
+		})
+		exitIfCancelled(err)
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			print.Message("I can't fetch %s, so its branches might be outdated: %s", print.Warning, remote.Name, err.Error())
+		}
+	}
+}
+
+// Delete branches on their remote, with the profile of each remote
+func deleteRemoteBranches(path string, upstreams []executor.Upstream) {
+	profiles := map[string]profile.Profile{}
+	for _, upstream := range upstreams {
+		profileLocal, ok := profiles[upstream.Remote]
+		if !ok {
+			profileLocal = getRemoteProfile(path, upstream.Remote)
+		}
+		err := runWithProgress("Deleting "+upstream.String(), func(ctx context.Context) error {
+			return executor.DeleteRemoteBranch(ctx, path, upstream.Remote, upstream.Branch, profileLocal.Username, profileLocal.Password)
+		})
+		if isAuthError(err) {
+			print.Message("Uh oh, your credentials are wrong for %s 😢. Please select another profile.", print.Error, upstream.Remote)
+			profileLocal = getSyncProfile(path, true)
+			err = runWithProgress("Deleting "+upstream.String(), func(ctx context.Context) error {
+				return executor.DeleteRemoteBranch(ctx, path, upstream.Remote, upstream.Branch, profileLocal.Username, profileLocal.Password)
+			})
+		}
+		profiles[upstream.Remote] = profileLocal
+		exitIfCancelled(err)
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			print.Message("I can't delete %s 😢: %s", print.Error, upstream.String(), err.Error())
+			continue
+		}
+		print.Message("Deleted %s", print.Success, upstream.String())
+	}
+}
@@ -1,16 +1,18 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"time"
+	"path/filepath"
 
-	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/go-git/go-git/plumbing/transport"
 
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
 	"github.com/julien040/gut/src/prompt"
 
 	"github.com/spf13/cobra"
@@ -47,7 +49,7 @@ func Clone(cmd *cobra.Command, args []string) {
 	if isPathGiven {
 		path = args[1]
 	}
-	path = makeValidPath(path, repoName)
+	path, created := makeValidPath(path, repoName)
 
 	isEmpty, err := isDirectoryEmpty(path)
 	if err != nil {
@@ -70,34 +72,66 @@ func Clone(cmd *cobra.Command, args []string) {
 	}
 
 	/* --------------------------------- Clone repo ------------------------------ */
-	fmt.Fprintf(color.Output, "\nYour repo is %s and will be cloned in %s", color.GreenString(repo), color.BlueString(path))
-	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond) // Build a new spinner
-	s.Start()
-	err = executor.Clone(repo, path, shouldConserveGitHistory)
-	s.Stop()
+	fmt.Fprintf(color.Output, "\nYour repo is %s and will be cloned in %s\n", color.GreenString(repo), color.BlueString(path))
+	err = runWithProgress("Cloning", func(ctx context.Context) error {
+		return executor.Clone(ctx, repo, path, shouldConserveGitHistory)
+	})
+	if errors.Is(err, errTransferCancelled) {
+		cleanCancelledClone(path, created, isEmpty)
+		exitOnError("", err)
+	}
 	if err != nil {
 		if err.Error() == "authentication required" {
 			print.Message("Oh no, this repo requires authentication 😓. Please enter your credentials", print.Info)
-			cloneRepoNeedsAuth(repo, path, shouldConserveGitHistory)
+			cloneRepoNeedsAuth(repo, path, shouldConserveGitHistory, created, isEmpty)
 		} else {
 			exitOnError("Sorry but I can't clone the repo 😓", err)
 		}
 	} else {
 		print.Message("I've successfully cloned your repo 🎉 at "+path, print.Success)
+		syncSubmodules(path, profile.Profile{})
 	}
 
 }
 
-func cloneRepoNeedsAuth(repo string, path string, shouldConserveGitHistory bool) {
+// created and wasEmpty tell what to clean up if the clone is cancelled (see cleanCancelledClone)
+func cloneRepoNeedsAuth(repo string, path string, shouldConserveGitHistory bool, created bool, wasEmpty bool) {
 	profile := selectProfile(repo, true)
-	err := executor.CloneWithAuth(repo, path, profile.Username, profile.Password, shouldConserveGitHistory)
+	err := runWithProgress("Cloning", func(ctx context.Context) error {
+		return executor.CloneWithAuth(ctx, repo, path, profile.Username, profile.Password, shouldConserveGitHistory)
+	})
+	if errors.Is(err, errTransferCancelled) {
+		cleanCancelledClone(path, created, wasEmpty)
+		exitOnError("", err)
+	}
 	if err == transport.ErrAuthorizationFailed {
 		print.Message("Uh oh, the credentials you entered are invalid. Please try again with a different profile 😉", print.Error)
-		cloneRepoNeedsAuth(repo, path, shouldConserveGitHistory)
+		cloneRepoNeedsAuth(repo, path, shouldConserveGitHistory, created, wasEmpty)
 	} else if err != nil {
 		exitOnError("I can't clone the repo 😓. Please make sure you have the right permissions", err)
 	} else {
 		print.Message("I've successfully cloned your repo 🎉 at "+path, print.Success)
 		associateProfileToPath(profile, path)
+		syncSubmodules(path, profile)
+	}
+}
+
+// Remove what a cancelled clone has written, so that the clone can be started again
+//
+// The directory is removed if gut created it, and emptied if it was empty. A directory that had files is left as it is
+func cleanCancelledClone(path string, created bool, wasEmpty bool) {
+	if created {
+		os.RemoveAll(path)
+		return
+	}
+	if !wasEmpty {
+		return
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(path, entry.Name()))
 	}
 }
@@ -13,7 +13,10 @@
 	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/spf13/cobra"
 	giturls "github.com/whilp/git-urls"
 
@@ -46,15 +49,17 @@ func getRepoNameFromURL(str string) string {
 }
 
 // Ask the user for a path to clone a repo and make sure it's valid
-func makeValidPath(originalPath string, repoName string) string {
+//
+// Return true if the directory has been created
+func makeValidPath(originalPath string, repoName string) (string, bool) {
 	// Check if the path exists
 	path := getAbsPathFromInput(repoName, originalPath)
 	if checkIfPathExist(path) {
-		return path
+		return path, false
 	} else {
 		// If the path doesn't exist, we ask the user if he wants to create it
 		if promptUserForMakingPath(path) {
-			return path
+			return path, true
 		} else {
 			return makeValidPath(askForPath(repoName, "Where do you want to clone the repo?"), repoName)
 		}
@@ -74,6 +79,15 @@ func getHost(str string) string {
 	}
 }
 
+// Return the path of the repository on the website (e.g. julien040/gut for https://github.com/julien040/gut.git)
+func getRepoPath(str string) string {
+	parsed, err := giturls.Parse(str)
+	if err != nil || parsed.Scheme == "" {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Trim(parsed.Path, "/"), ".git")
+}
+
 func isEmailValid(email string) bool {
 	_, err := mail.ParseAddress(email)
 	return err == nil
@@ -194,6 +208,7 @@ func checkIfDetachedHead(wd string) {
 		if err != nil {
 			exitOnError("Oups, something went wrong while I was checking out the branch", err)
 		}
+		recordCheckout(wd, "", branch)
 		print.Message("You are now on branch %s and you can continue working 🎉", print.Success, branch)
 		os.Exit(0)
 
@@ -209,6 +224,7 @@ func checkIfDetachedHead(wd string) {
 				print.Message("Oups, you need to enter a name for the branch", print.Error)
 				return promptUser()
 			}
+			branchName = enforceBranchConvention(wd, branchName)
 			// Check if the branch already exists
 			exists, err := executor.CheckIfBranchExists(wd, branchName)
 			if err != nil {
@@ -227,6 +243,7 @@ func checkIfDetachedHead(wd string) {
 		if err != nil {
 			exitOnError("Oups, something went wrong while I was creating the new branch", err)
 		}
+		recordCheckout(wd, "", branchName)
 		print.Message("You are now on branch %s and you can continue working 🎉", print.Success, branchName)
 		os.Exit(0)
 
@@ -236,3 +253,48 @@ func checkIfDetachedHead(wd string) {
 	}
 
 }
+
+// Print a list of commits with their author and date
+func printCommitList(commits []object.Commit) {
+	for _, commit := range commits {
+		fmt.Fprintf(color.Output, "\t%s %s by %s on %s\n", color.HiYellowString(commit.Hash.String()[:7]), color.HiCyanString(getTitleFromCommit(commit.Message)), commit.Author.Name, commit.Author.When.Format("Mon Jan 2 15:04:05"))
+	}
+}
+
+// Warn the user that the commits they are about to change have already been synced with the remote
+//
+// Return true if they still want to rewrite them
+func confirmRewritePushedCommits() bool {
+	print.Message("⚠️  Some of these commits have already been synced with the remote.", print.Warning)
+	print.Message("Rewriting them changes their hash. On your next gut sync, I'll ask you to replace the remote branch with your history.\nIf someone has based their work on these commits, they will have to fix their history.", print.Warning)
+	res, err := prompt.InputBool("Do you really want to rewrite commits that have been synced?", false)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	return res
+}
+
+// Describe how long ago a date was (e.g. 3 days ago)
+func formatAge(date time.Time, now time.Time) string {
+	elapsed := now.Sub(date)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return plural(int(elapsed.Minutes()), "minute")
+	case elapsed < 24*time.Hour:
+		return plural(int(elapsed.Hours()), "hour")
+	case elapsed < 30*24*time.Hour:
+		return plural(int(elapsed.Hours()/24), "day")
+	case elapsed < 365*24*time.Hour:
+		return plural(int(elapsed.Hours()/24/30), "month")
+	default:
+		return plural(int(elapsed.Hours()/24/365), "year")
+	}
+}
@@ -5,9 +5,27 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/provider"
 )
 
+// Fake hash made of one character (e.g. aaa... for 'a'), to build commits and steps
+func testHash(c byte) plumbing.Hash {
+	return plumbing.NewHash(strings.Repeat(string(c), 40))
+}
+
 func Test_checkURL(t *testing.T) {
 	type args struct {
 		str string
@@ -308,3 +326,644 @@ type args struct {
 		})
 	}
 }
+
+func Test_formatAge(t *testing.T) {
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+	type args struct {
+		date time.Time
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "A few seconds",
+			args: args{
+				date: now.Add(-10 * time.Second),
+			},
+			want: "just now",
+		},
+		{
+			name: "One minute",
+			args: args{
+				date: now.Add(-time.Minute),
+			},
+			want: "1 minute ago",
+		},
+		{
+			name: "Several hours",
+			args: args{
+				date: now.Add(-5 * time.Hour),
+			},
+			want: "5 hours ago",
+		},
+		{
+			name: "Several days",
+			args: args{
+				date: now.Add(-3 * 24 * time.Hour),
+			},
+			want: "3 days ago",
+		},
+		{
+			name: "Several months",
+			args: args{
+				date: now.Add(-65 * 24 * time.Hour),
+			},
+			want: "2 months ago",
+		},
+		{
+			name: "One year",
+			args: args{
+				date: now.Add(-400 * 24 * time.Hour),
+			},
+			want: "1 year ago",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatAge(tt.args.date, now); got != tt.want {
+				t.Errorf("formatAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_slugify(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "Sentence", text: "Fix the login page!", want: "fix-the-login-page"},
+		{name: "Separators are merged", text: "  add -- OAuth/SSO  ", want: "add-oauth-sso"},
+		{name: "Already a slug", text: "add-login", want: "add-login"},
+		{name: "No letter", text: "?!", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slugify(tt.text); got != tt.want {
+				t.Errorf("slugify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildBranchName(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention string
+		ticket     string
+		want       string
+	}{
+		{name: "With ticket", convention: "{type}/{ticket}-{slug}", ticket: "ABC-12", want: "feat/ABC-12-add-login"},
+		{name: "Without ticket", convention: "{type}/{ticket}-{slug}", ticket: "", want: "feat/add-login"},
+		{name: "Without placeholder for the ticket", convention: "{type}/{slug}", ticket: "ABC-12", want: "feat/add-login"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildBranchName(tt.convention, "feat", tt.ticket, "add-login"); got != tt.want {
+				t.Errorf("buildBranchName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchBranchConvention(t *testing.T) {
+	conf := profile.BranchConf{Convention: "{type}/{ticket}-{slug}", Types: []string{"feat", "fix"}}
+	tests := []struct {
+		name   string
+		conf   profile.BranchConf
+		branch string
+		want   bool
+	}{
+		{name: "No convention", conf: profile.BranchConf{}, branch: "anything", want: true},
+		{name: "With ticket", conf: conf, branch: "feat/ABC-12-add-login", want: true},
+		{name: "Without ticket", conf: conf, branch: "fix/add-login", want: true},
+		{name: "Unknown type", conf: conf, branch: "docs/add-login", want: false},
+		{name: "Not a slug", conf: conf, branch: "feat/Add_Login", want: false},
+		{name: "Missing type", conf: conf, branch: "add-login", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchBranchConvention(tt.conf, tt.branch); got != tt.want {
+				t.Errorf("matchBranchConvention() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		want    bool
+	}{
+		{name: "Empty pattern", pattern: "", text: "main", want: true},
+		{name: "Letters in order", pattern: "fln", text: "feat/login", want: true},
+		{name: "Case insensitive", pattern: "FEAT", text: "feat/login", want: true},
+		{name: "Letters out of order", pattern: "nlf", text: "feat/login", want: false},
+		{name: "Missing letter", pattern: "fix", text: "feat/login", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fuzzyMatch(tt.pattern, tt.text); got != tt.want {
+				t.Errorf("fuzzyMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeCommitMessage(t *testing.T) {
+	commits := []object.Commit{{Message: "Add the login page\n\nWith a form"}, {Message: "Fix typo"}}
+	want := "🔀 Merge feat into main\n\n- Add the login page\n- Fix typo"
+	if got := mergeCommitMessage("feat", "main", commits); got != want {
+		t.Errorf("mergeCommitMessage() = %q, want %q", got, want)
+	}
+
+	many := make([]object.Commit, maxCommitsInMergeMessage+3)
+	if got := mergeCommitMessage("feat", "main", many); !strings.HasSuffix(got, "\n- and 3 more") {
+		t.Errorf("mergeCommitMessage() = %q, want the number of commits not listed", got)
+	}
+}
+
+func Test_getRepoPath(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "HTTPS", url: "https://github.com/julien040/gut.git", want: "julien040/gut"},
+		{name: "HTTPS without .git", url: "https://gitlab.com/group/sub/repo", want: "group/sub/repo"},
+		{name: "SSH", url: "git@bitbucket.org:team/repo.git", want: "team/repo"},
+		{name: "Invalid URL", url: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getRepoPath(tt.url); got != tt.want {
+				t.Errorf("getRepoPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pullRequestFromCommits(t *testing.T) {
+	one := []object.Commit{{Message: "Add the login page\n\nWith a form\n"}}
+	title, body := pullRequestFromCommits("feat/login", one)
+	if title != "Add the login page" || body != "With a form" {
+		t.Errorf("pullRequestFromCommits() = %q, %q, want the message of the commit", title, body)
+	}
+
+	// Newest first, like git log
+	several := []object.Commit{{Message: "Fix typo"}, {Message: "Add the form\n\nDetails"}}
+	title, body = pullRequestFromCommits("feat/ABC-12-add_login-page", several)
+	if title != "ABC 12 add login page" || body != "- Add the form\n- Fix typo" {
+		t.Errorf("pullRequestFromCommits() = %q, %q, want the branch name and the list of commits", title, body)
+	}
+}
+
+func Test_getProvider(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	err := profile.SaveHosts([]profile.HostConf{
+		{Host: "git.company.com", Provider: "gitlab"},
+		{Host: "github.com", Provider: "gitea", API: "https://mirror.example.com/api/v1"},
+	})
+	if err != nil {
+		t.Fatalf("SaveHosts() error = %v", err)
+	}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"git.company.com", "GitLab"},
+		{"GIT.company.com", "GitLab"},
+		{"github.com", "Gitea"},
+		{"bitbucket.org", "Bitbucket"},
+		{"codeberg.org", "Gitea"},
+		{"example.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := getProvider(tt.host)
+			if err != nil {
+				t.Fatalf("getProvider() error = %v", err)
+			}
+			name := ""
+			if got != nil {
+				name = got.Name()
+			}
+			if name != tt.want {
+				t.Errorf("getProvider() = %v, want %v", name, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parsePullRequestNumber(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    int
+		wantErr bool
+	}{
+		{"12", 12, false},
+		{"#12", 12, false},
+		{"https://github.com/julien040/gut/pull/12", 12, false},
+		{"https://gitlab.com/group/repo/-/merge_requests/7/", 7, false},
+		{"feat", 0, true},
+		{"0", 0, true},
+		{"-3", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parsePullRequestNumber(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePullRequestNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePullRequestNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pullRequestBranchName(t *testing.T) {
+	tests := []struct {
+		pr   provider.PullRequest
+		want string
+	}{
+		{provider.PullRequest{Number: 3, Head: "feat/login"}, "feat/login"},
+		{provider.PullRequest{Number: 4, Head: "main", Fork: true}, "pr/4"},
+		{provider.PullRequest{Number: 5}, "pr/5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := pullRequestBranchName(tt.pr); got != tt.want {
+				t.Errorf("pullRequestBranchName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_planCommitMove(t *testing.T) {
+	// e <- d <- c <- b <- a (root), newest first
+	linear := []object.Commit{
+		{Hash: testHash('e'), ParentHashes: []plumbing.Hash{testHash('d')}},
+		{Hash: testHash('d'), ParentHashes: []plumbing.Hash{testHash('c')}},
+		{Hash: testHash('c'), ParentHashes: []plumbing.Hash{testHash('b')}},
+		{Hash: testHash('b'), ParentHashes: []plumbing.Hash{testHash('a')}},
+		{Hash: testHash('a')},
+	}
+	merge := []object.Commit{
+		{Hash: testHash('e'), ParentHashes: []plumbing.Hash{testHash('d')}},
+		{Hash: testHash('d'), ParentHashes: []plumbing.Hash{testHash('c'), testHash('f')}},
+		{Hash: testHash('c'), ParentHashes: []plumbing.Hash{testHash('b')}},
+	}
+	tests := []struct {
+		name       string
+		commits    []object.Commit
+		selected   []byte
+		wantBase   byte
+		wantMove   []byte
+		wantReplay []byte
+		wantErr    bool
+	}{
+		{"last commit", linear, []byte{'e'}, 'd', []byte{'e'}, nil, false},
+		{"last two", linear, []byte{'e', 'd'}, 'c', []byte{'d', 'e'}, nil, false},
+		{"older commit", linear, []byte{'c'}, 'b', []byte{'c'}, []byte{'d', 'e'}, false},
+		{"not contiguous", linear, []byte{'e', 'c'}, 'b', []byte{'c', 'e'}, []byte{'d'}, false},
+		{"root", linear, []byte{'a'}, 0, nil, nil, true},
+		{"nothing", linear, nil, 0, nil, nil, true},
+		{"after the merge", merge, []byte{'e'}, 'd', []byte{'e'}, nil, false},
+		{"before the merge", merge, []byte{'c'}, 0, nil, nil, true},
+	}
+	toHashes := func(commits []byte) []string {
+		var hashes []string
+		for _, c := range commits {
+			hashes = append(hashes, testHash(c).String())
+		}
+		return hashes
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := map[string]bool{}
+			for _, hash := range toHashes(tt.selected) {
+				selected[hash] = true
+			}
+			base, move, replay, err := planCommitMove(tt.commits, selected)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planCommitMove() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if base != testHash(tt.wantBase).String() || !reflect.DeepEqual(move, toHashes(tt.wantMove)) || !reflect.DeepEqual(replay, toHashes(tt.wantReplay)) {
+				t.Errorf("planCommitMove() = %v, %v, %v", base, move, replay)
+			}
+		})
+	}
+}
+
+func Test_commitsAfter(t *testing.T) {
+	// Newest first
+	commits := []object.Commit{{Hash: testHash('c')}, {Hash: testHash('b')}, {Hash: testHash('a')}}
+	tests := []struct {
+		name   string
+		commit byte
+		want   []byte
+	}{
+		{"last commit", 'c', nil},
+		{"middle commit", 'b', []byte{'c'}},
+		{"oldest commit", 'a', []byte{'b', 'c'}},
+		{"unknown commit", 'f', nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []string
+			for _, c := range tt.want {
+				want = append(want, testHash(c).String())
+			}
+			if got := commitsAfter(commits, testHash(tt.commit)); !reflect.DeepEqual(got, want) {
+				t.Errorf("commitsAfter() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_buildRebaseTodo(t *testing.T) {
+	step := func(c byte, action string) rewriteStep {
+		return rewriteStep{Hash: testHash(c).String(), Title: "commit " + string(c), Action: action}
+	}
+	messageFile := func(i int) string {
+		return "/tmp/it's/" + string(rune('0'+i))
+	}
+	tests := []struct {
+		name  string
+		steps []rewriteStep
+		want  []string
+	}{
+		{"keep", []rewriteStep{step('a', rewritePick), step('b', rewritePick)}, []string{
+			"pick " + testHash('a').String() + " commit a",
+			"pick " + testHash('b').String() + " commit b",
+		}},
+		{"drop and edit", []rewriteStep{step('a', rewriteDrop), step('b', rewriteEdit)}, []string{
+			"drop " + testHash('a').String() + " commit a",
+			"edit " + testHash('b').String() + " commit b",
+		}},
+		{"reword after squash", []rewriteStep{step('a', rewriteReword), step('b', rewriteSquash), step('c', rewriteReword)}, []string{
+			"pick " + testHash('a').String() + " commit a",
+			"squash " + testHash('b').String() + " commit b",
+			`exec git commit --amend --only --allow-empty --quiet -F '/tmp/it'\''s/0'`,
+			"pick " + testHash('c').String() + " commit c",
+			`exec git commit --amend --only --allow-empty --quiet -F '/tmp/it'\''s/2'`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := strings.Join(tt.want, "\n") + "\n"
+			if got := buildRebaseTodo(tt.steps, messageFile); got != want {
+				t.Errorf("buildRebaseTodo() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func Test_previewRewrite(t *testing.T) {
+	step := func(c byte, action string) rewriteStep {
+		return rewriteStep{Hash: testHash(c).String(), Title: "commit " + string(c), Action: action, Message: "new " + string(c) + "\n\nbody"}
+	}
+	steps := []rewriteStep{step('a', rewriteReword), step('b', rewriteSquash), step('c', rewriteDrop), step('d', rewriteEdit), step('e', rewritePick)}
+	want := []string{
+		"eeeeeee commit e",
+		"ddddddd commit d (paused to edit)",
+		"aaaaaaa new a (reworded, +1 squashed)",
+	}
+	if got := previewRewrite(steps); !reflect.DeepEqual(got, want) {
+		t.Errorf("previewRewrite() = %v, want %v", got, want)
+	}
+	if err := validateRewrite(steps); err != nil {
+		t.Errorf("validateRewrite() error = %v", err)
+	}
+	if err := validateRewrite([]rewriteStep{step('a', rewriteDrop), step('b', rewriteSquash)}); err == nil {
+		t.Error("validateRewrite() should refuse to squash into a dropped commit")
+	}
+}
+
+func Test_planAutosquash(t *testing.T) {
+	step := func(c byte, title string) rewriteStep {
+		return rewriteStep{Hash: testHash(c).String(), Title: title, Action: rewritePick}
+	}
+	steps := []rewriteStep{
+		step('a', "Add login"),
+		step('b', "Add logout"),
+		step('c', "fixup! Add login"),
+		step('d', "fixup! bbbbbbb"),
+		step('e', "fixup! fixup! Add login"),
+		step('f', "fixup! Pushed commit"),
+	}
+	got, matched := planAutosquash(steps)
+	want := []string{"a keep", "c fixup", "e fixup", "b keep", "d fixup", "f keep"}
+	var order []string
+	for _, s := range got {
+		order = append(order, s.Hash[:1]+" "+s.Action)
+	}
+	if !reflect.DeepEqual(order, want) || matched != 3 {
+		t.Errorf("planAutosquash() = %v, %d, want %v, 3", order, matched, want)
+	}
+	if _, matched := planAutosquash(steps[:2]); matched != 0 {
+		t.Errorf("planAutosquash() matched %d commits without fixups", matched)
+	}
+}
+
+func Test_fixupTitle(t *testing.T) {
+	commit := func(c byte, message string) object.Commit {
+		return object.Commit{Hash: testHash(c), Message: message}
+	}
+	// Newest first
+	commits := []object.Commit{
+		commit('d', "Fix typo\n\nIn the README"),
+		commit('c', "fixup! Add login"),
+		commit('b', "Fix typo"),
+		commit('a', "Add login"),
+	}
+	tests := []struct {
+		target object.Commit
+		want   string
+	}{
+		{commits[3], "fixup! Add login"},
+		{commits[0], "fixup! Fix typo"},
+		{commits[2], "fixup! bbbbbbb"},
+		{commits[1], "fixup! fixup! Add login"},
+	}
+	for _, tt := range tests {
+		if got := fixupTitle(commits, tt.target); got != tt.want {
+			t.Errorf("fixupTitle() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func Test_describeStateChange(t *testing.T) {
+	a, b := testHash('a').String(), testHash('b').String()
+	before := executor.RepoState{Head: "refs/heads/main", HeadHash: a, Refs: map[string]string{"refs/heads/main": a, "refs/heads/old": a}, Index: "i1", Worktree: "w1"}
+	tests := []struct {
+		name  string
+		after executor.RepoState
+		want  []string
+	}{
+		{"nothing", before, nil},
+		{"save", executor.RepoState{Head: "refs/heads/main", HeadHash: b, Refs: map[string]string{"refs/heads/main": b, "refs/heads/old": a}, Index: "i2", Worktree: "w1"}, []string{
+			"move the branch main from aaaaaaa to bbbbbbb",
+			"change the staged files",
+		}},
+		{"switch", executor.RepoState{Head: "refs/heads/new", HeadHash: a, Refs: map[string]string{"refs/heads/main": a, "refs/heads/new": a, "refs/stash": b}, Index: "i1", Worktree: "w2"}, []string{
+			"switch to the branch new",
+			"create the branch new at aaaaaaa",
+			"delete the branch old (at aaaaaaa)",
+			"create the stash at bbbbbbb",
+			"change the files of the working tree",
+		}},
+		{"detached", executor.RepoState{HeadHash: b, Refs: before.Refs, Index: "i1", Worktree: "w1"}, []string{
+			"switch to the commit bbbbbbb",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeStateChange(before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("describeStateChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_explainLostCommits(t *testing.T) {
+	a, b, c := testHash('a').String(), testHash('b').String(), testHash('c').String()
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	lost := map[string]bool{a: true, b: true}
+	reflogs := map[string][]executor.ReflogEntry{
+		"HEAD": {
+			{Old: c, New: a, When: day(1), Message: "commit: add a"},
+			{Old: a, New: c, When: day(2), Message: "reset: moving to HEAD~1"},
+		},
+	}
+	journal := []executor.JournalEntry{
+		{When: day(3), Command: "gut squash", Before: executor.RepoState{HeadHash: b, Refs: map[string]string{"refs/heads/main": b}}, After: executor.RepoState{HeadHash: c, Refs: map[string]string{"refs/heads/main": c}}},
+		{When: day(4), Command: "gut switch main", Before: executor.RepoState{HeadHash: c, Refs: map[string]string{"refs/heads/main": c}}, After: executor.RepoState{HeadHash: c, Refs: map[string]string{"refs/heads/main": c}}},
+	}
+	want := map[string]lostReason{
+		a: {Operation: "reset: moving to HEAD~1", When: day(2)},
+		b: {Operation: "gut squash", When: day(3)},
+	}
+	if got := explainLostCommits(lost, reflogs, journal); !reflect.DeepEqual(got, want) {
+		t.Errorf("explainLostCommits() = %v, want %v", got, want)
+	}
+}
+
+func Test_getSnapshotRetention(t *testing.T) {
+	tests := []struct {
+		days int
+		want time.Duration
+	}{
+		{0, 14 * 24 * time.Hour},
+		{3, 3 * 24 * time.Hour},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		wd := t.TempDir()
+		if err := profile.SaveGutConf(wd, profile.SchemaGutConf{Snapshot: profile.SnapshotConf{RetentionDays: tt.days}}); err != nil {
+			t.Fatal(err)
+		}
+		if got := getSnapshotRetention(wd); got != tt.want {
+			t.Errorf("getSnapshotRetention() with %d days = %v, want %v", tt.days, got, tt.want)
+		}
+	}
+}
+
+func Test_cancelledError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	failure := errors.New("connection refused")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{"success", context.Background(), nil, nil},
+		{"failure", context.Background(), failure, failure},
+		{"cancelled", cancelled, failure, errTransferCancelled},
+		{"done before being cancelled", cancelled, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := cancelledError(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("cancelledError() %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_wasBranchTip(t *testing.T) {
+	reflog := []executor.ReflogEntry{{Old: "a", New: "b"}}
+	journal := []executor.JournalEntry{
+		{Before: executor.RepoState{Refs: map[string]string{"refs/heads/main": "c"}}, After: executor.RepoState{Refs: map[string]string{"refs/heads/main": "d"}}},
+		{Before: executor.RepoState{Refs: map[string]string{"refs/heads/feature": "e"}}},
+	}
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{"a", true},
+		{"b", true},
+		{"c", true},
+		{"d", true},
+		// Tip of another branch
+		{"e", false},
+		// Commits from someone else, fetched but never checked out
+		{"f", false},
+	}
+	for _, tt := range tests {
+		if got := wasBranchTip("main", tt.hash, reflog, journal); got != tt.want {
+			t.Errorf("wasBranchTip(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func Test_cleanCancelledClone(t *testing.T) {
+	tests := []struct {
+		name     string
+		created  bool
+		wasEmpty bool
+		want     []string
+	}{
+		{"created by gut", true, true, nil},
+		{"empty before", false, true, []string{}},
+		{"files before", false, false, []string{".git", "README.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "repo")
+			if err := os.MkdirAll(filepath.Join(path, ".git"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(path, "README.md"), []byte("# repo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cleanCancelledClone(path, tt.created, tt.wasEmpty)
+			entries, err := os.ReadDir(path)
+			if os.IsNotExist(err) {
+				if tt.want != nil {
+					t.Errorf("cleanCancelledClone() removed the directory")
+				}
+				return
+			}
+			got := []string{}
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cleanCancelledClone() left %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// Placeholders of a naming convention (e.g. {type}/{ticket}-{slug})
+var conventionPlaceholder = regexp.MustCompile(`\{(type|ticket|slug)\}`)
+
+// A ticket id looks like ABC-123 or 123
+var ticketRegexp = regexp.MustCompile(`^[A-Za-z0-9]+(?:-[0-9]+)?$`)
+
+const (
+	ticketExpr = `[A-Za-z0-9]+(?:-[0-9]+)?`
+	slugExpr   = `[a-z0-9]+(?:-[a-z0-9]+)*`
+	// Characters that can separate {ticket} from the next placeholder
+	ticketSeparators = "-_/."
+)
+
+// Values of base in the [branch] section of the .gut file
+const (
+	branchBaseDefault = "default"
+	branchBaseCurrent = "current"
+)
+
+// Types offered when the .gut file doesn't list any
+var defaultBranchTypes = []string{"feat", "fix", "chore", "docs", "refactor", "test"}
+
+// Convert a text to lowercase words separated by dashes (e.g. "Fix the login page!" => "fix-the-login-page")
+func slugify(text string) string {
+	var slug strings.Builder
+	separate := false
+	for _, r := range strings.ToLower(text) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if separate && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			separate = false
+			slug.WriteRune(r)
+		} else {
+			separate = true
+		}
+	}
+	return slug.String()
+}
+
+// Replace the placeholders of the convention with the result of replace
+//
+// The separator following {ticket} (e.g. the dash of {ticket}-{slug}) is passed with it,
+// so that it can be omitted when there is no ticket. quote is applied to the rest of the text
+func expandConvention(convention string, quote func(string) string, replace func(placeholder string, separator string) string) string {
+	var expanded strings.Builder
+	rest := convention
+	for {
+		loc := conventionPlaceholder.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			expanded.WriteString(quote(rest))
+			return expanded.String()
+		}
+		expanded.WriteString(quote(rest[:loc[0]]))
+		placeholder := rest[loc[2]:loc[3]]
+		rest = rest[loc[1]:]
+		separator := ""
+		if placeholder == "ticket" && rest != "" && strings.ContainsRune(ticketSeparators, rune(rest[0])) {
+			separator, rest = rest[:1], rest[1:]
+		}
+		expanded.WriteString(replace(placeholder, separator))
+	}
+}
+
+// Return true if the branch name follows the convention. Any name is accepted without convention
+func matchBranchConvention(conf profile.BranchConf, name string) bool {
+	if conf.Convention == "" {
+		return true
+	}
+	typeExpr := `[a-z]+`
+	if len(conf.Types) > 0 {
+		quoted := make([]string, len(conf.Types))
+		for i, branchType := range conf.Types {
+			quoted[i] = regexp.QuoteMeta(branchType)
+		}
+		typeExpr = "(?:" + strings.Join(quoted, "|") + ")"
+	}
+	expr := expandConvention(conf.Convention, regexp.QuoteMeta, func(placeholder string, separator string) string {
+		switch placeholder {
+		case "type":
+			return typeExpr
+		case "ticket":
+			return "(?:" + ticketExpr + regexp.QuoteMeta(separator) + ")?"
+		}
+		return slugExpr
+	})
+	matched, err := regexp.MatchString("^"+expr+"$", name)
+	return err == nil && matched
+}
+
+// Fill the placeholders of the convention. If ticket is empty, it's omitted with its separator
+func buildBranchName(convention string, branchType string, ticket string, slug string) string {
+	keep := func(text string) string { return text }
+	return expandConvention(convention, keep, func(placeholder string, separator string) string {
+		switch placeholder {
+		case "type":
+			return branchType
+		case "ticket":
+			if ticket == "" {
+				return ""
+			}
+			return ticket + separator
+		}
+		return slug
+	})
+}
+
+// Return the [branch] section of the .gut file
+func getBranchConf(wd string) profile.BranchConf {
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	return conf.Branch
+}
+
+// Ask the type, the ticket and the description of the branch, and name it after the convention
+//
+// description is the default value of the description
+func askBranchNameFromConvention(conf profile.BranchConf, description string) string {
+	var branchType, ticket string
+	var err error
+	if strings.Contains(conf.Convention, "{type}") {
+		types := conf.Types
+		if len(types) == 0 {
+			types = defaultBranchTypes
+		}
+		branchType, err = prompt.InputSelect("Type of the branch:", types)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	if strings.Contains(conf.Convention, "{ticket}") {
+		err = survey.AskOne(&survey.Input{
+			Message: "Ticket id (leave empty if there is none):",
+		}, &ticket, survey.WithValidator(func(ans interface{}) error {
+			if text := ans.(string); text != "" && !ticketRegexp.MatchString(text) {
+				return errors.New("a ticket id looks like ABC-123 or 123")
+			}
+			return nil
+		}))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	err = survey.AskOne(&survey.Input{
+		Message: "Short description of the branch:",
+		Default: description,
+	}, &description, survey.WithValidator(func(ans interface{}) error {
+		if slugify(ans.(string)) == "" {
+			return errors.New("the description needs at least a letter or a digit")
+		}
+		return nil
+	}))
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+
+	name := buildBranchName(conf.Convention, branchType, ticket, slugify(description))
+	if executor.ValidateBranchName(name) != nil {
+		exitOnError(name+" isn't a valid branch name. Check the convention in the .gut file", nil)
+	}
+	print.Message("The branch will be named %s", print.Info, name)
+	return name
+}
+
+// Return a name following the naming convention of the repository
+//
+// If name doesn't follow it, the user builds a new one from its type, ticket and description
+func enforceBranchConvention(wd string, name string) string {
+	conf := getBranchConf(wd)
+	if matchBranchConvention(conf, name) {
+		return name
+	}
+	print.Message("%s doesn't follow the naming convention of the repository (%s)", print.Warning, name, conf.Convention)
+	return askBranchNameFromConvention(conf, name)
+}
+
+// Return the branch new branches must start from
+//
+// Empty if they start from the current branch
+func getNewBranchBase(wd string) string {
+	if getBranchConf(wd).Base != branchBaseDefault {
+		return ""
+	}
+	defaultBranch, err := executor.GetDefaultBranch(wd)
+	if err != nil {
+		exitOnError("I can't find the default branch", err)
+	}
+	currentBranch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("I can't get the current branch", err)
+	}
+	if defaultBranch == currentBranch {
+		return ""
+	}
+	return defaultBranch
+}
+
+// Set the naming convention of the branches and the branch they start from
+func BranchConvention(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	if conf.Branch.Convention != "" {
+		print.Message("Current convention: %s", print.Info, conf.Branch.Convention)
+	}
+
+	const (
+		custom = "Custom"
+		none   = "No convention"
+	)
+	res, err := prompt.InputSelect("How do you want to name the branches?", []string{"{type}/{ticket}-{slug}", "{type}/{slug}", custom, none})
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	switch res {
+	case none:
+		conf.Branch.Convention = ""
+		conf.Branch.Types = nil
+	case custom:
+		err = survey.AskOne(&survey.Input{
+			Message: "Convention (placeholders: {type}, {ticket}, {slug}):",
+			Default: conf.Branch.Convention,
+		}, &conf.Branch.Convention, survey.WithValidator(func(ans interface{}) error {
+			convention := ans.(string)
+			if !strings.Contains(convention, "{slug}") {
+				return errors.New("the convention must contain {slug}")
+			}
+			if executor.ValidateBranchName(buildBranchName(convention, "feat", "ABC-123", "add-login-page")) != nil {
+				return errors.New("the branch names wouldn't be valid")
+			}
+			return nil
+		}))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	default:
+		conf.Branch.Convention = res
+	}
+
+	if strings.Contains(conf.Branch.Convention, "{type}") {
+		types := conf.Branch.Types
+		if len(types) == 0 {
+			types = defaultBranchTypes
+		}
+		var typesInput string
+		err = survey.AskOne(&survey.Input{
+			Message: "Types allowed, separated by commas:",
+			Default: strings.Join(types, ", "),
+		}, &typesInput, survey.WithValidator(survey.Required))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		conf.Branch.Types = nil
+		for _, branchType := range strings.Split(typesInput, ",") {
+			if slug := slugify(branchType); slug != "" {
+				conf.Branch.Types = append(conf.Branch.Types, slug)
+			}
+		}
+	}
+
+	const (
+		fromCurrent = "The current branch"
+		fromDefault = "The default branch"
+	)
+	res, err = prompt.InputSelect("Which branch should new branches start from?", []string{fromCurrent, fromDefault})
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	conf.Branch.Base = branchBaseCurrent
+	if res == fromDefault {
+		conf.Branch.Base = branchBaseDefault
+	}
+
+	err = profile.SaveGutConf(wd, conf)
+	if err != nil {
+		exitOnError("Sorry, I can't save the .gut file 😢", err)
+	}
+	if conf.Branch.Convention == "" {
+		print.Message("Branches can be named freely", print.Success)
+		return
+	}
+	example := buildBranchName(conf.Branch.Convention, "feat", "ABC-123", "add-login-page")
+	if len(conf.Branch.Types) > 0 {
+		example = buildBranchName(conf.Branch.Convention, conf.Branch.Types[0], "ABC-123", "add-login-page")
+	}
+	print.Message("New branches will be named like %s 🎉", print.Success, example)
+}
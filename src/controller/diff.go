@@ -1,8 +1,6 @@
 package controller
 
 import (
-	"fmt"
-
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
@@ -63,7 +61,7 @@ func diffCommitAndWorkingTree(wd string, commit string) {
 }
 
 func diffTwoCommits(wd string, commit1 string, commit2 string) {
-	// This command is slightly different. If using GitHub or GitLab, it will show the difference with the browser.
+	// This command is slightly different. If the website hosting the repository is known, it will show the difference with the browser.
 	// If using a local repository, it will show the difference with the terminal.
 
 	// Check if the two commits have been pushed
@@ -91,25 +89,19 @@ func diffTwoCommits(wd string, commit1 string, commit2 string) {
 	// If both refs are pushed, we can use the browser
 	if existsOnRemote1 && existsOnRemote2 {
 
-		// Get the host
-		platform, remoteURL, err := getPlatformUsed(wd)
+		// Get the website hosting the repository
+		hosting, repoPath, err := getPlatformUsed(wd)
 		if err != nil {
 			exitOnError("Sorry, I can't get the platform used", err)
 		}
-		switch platform {
-		case "github.com":
-			// https://docs.github.com/en/pull-requests/committing-changes-to-your-project/viewing-and-comparing-commits/comparing-commits
-			urlToOpen := fmt.Sprintf("%s/compare/%s..%s", remoteURL, commit1, commit2)
-			print.Message("Opening %s", print.Optional, color.BlueString(urlToOpen))
-			openInBrowser(urlToOpen)
-
-		case "gitlab.com":
-			// https://stackoverflow.com/a/50070145/15573415
-			urlToOpen := fmt.Sprintf("%s/compare?from=%s&to=%s", remoteURL, commit1, commit2)
+		urlToOpen := ""
+		if hosting != nil {
+			urlToOpen = hosting.DiffURL(repoPath, commit1, commit2)
+		}
+		if urlToOpen != "" {
 			print.Message("Opening %s", print.Optional, color.BlueString(urlToOpen))
 			openInBrowser(urlToOpen)
-
-		default:
+		} else {
 			empty, _ := executor.GitDiffRef(commit1, commit2)
 			if empty {
 				print.Message("No changes between %s and %s", print.Warning, commit1, commit2)
@@ -7,6 +7,8 @@
 	"errors"
 
 	"github.com/fatih/color"
+
+	"github.com/julien040/gut/src/print"
 )
 
 var (
@@ -30,6 +32,13 @@ type GutError struct {
 
 // An helper function to exit the program if an error occurs
 func exitOnError(str string, err error) {
+	// The user has pressed Ctrl-C, it's not an error
+	if errors.Is(err, errTransferCancelled) {
+		print.Message("\nOkay, I've cancelled the transfer", print.Warning)
+		recordJournal()
+		os.Exit(1)
+	}
+
 	// Print the error message to stderr
 
 	// Print a new line
@@ -43,6 +52,8 @@ func exitOnError(str string, err error) {
 		fmt.Fprintf(os.Stderr, "%s\n", "If this error persists, please open an issue on GitHub: https://github.com/julien040/gut/issues/new")
 	}
 
+	// What the command did before failing can still be undone
+	recordJournal()
 	os.Exit(1)
 }
 
@@ -60,6 +71,7 @@ func exitOnKnownError(typeOfError GutError, err error) {
 	// When the error is linked to the user input, we don't print the error message
 	// because it's not useful
 	if typeOfError.Code == 1 {
+		recordJournal()
 		os.Exit(1)
 		return
 	}
@@ -72,5 +84,6 @@ func exitOnKnownError(typeOfError GutError, err error) {
 	}
 	fmt.Fprintf(os.Stderr, "To resolve this issue, please follow the instructions on this page: %s\n", getLinkForError(typeOfError))
 
+	recordJournal()
 	os.Exit(1)
 }
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+)
+
+// Update the remote-tracking branches without changing the local branches
+func Fetch(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+
+	prune, _ := cmd.Flags().GetBool("prune")
+	all, _ := cmd.Flags().GetBool("all")
+
+	var remotes []executor.Remote
+	if all {
+		list, err := executor.ListRemote(wd)
+		if err != nil {
+			exitOnError("Sorry, I can't get the remotes of the repository 😢", err)
+		}
+		if len(list) == 0 {
+			print.Message("You don't have any remote yet. Add one with gut remote add", print.Warning)
+			return
+		}
+		remotes = list
+	} else if len(args) > 0 {
+		list, err := executor.ListRemote(wd)
+		if err != nil {
+			exitOnError("Sorry, I can't get the remotes of the repository 😢", err)
+		}
+		remote, ok := findRemote(list, args[0])
+		if !ok {
+			exitOnError("The remote "+args[0]+" doesn't exist. You can list them with gut remote", nil)
+		}
+		remotes = []executor.Remote{remote}
+	} else {
+		remotes = []executor.Remote{getCurrentRemote(wd)}
+	}
+
+	for _, remote := range remotes {
+		fetchRemote(wd, remote, prune, getRemoteProfile(wd, remote.Name))
+	}
+}
+
+// Get the remote of the current branch
+//
+// In detached HEAD state, or if the branch doesn't track any remote branch, we fall back on getRemote
+func getCurrentRemote(path string) executor.Remote {
+	var remote executor.Remote
+	var err error
+	detached, _ := executor.IsDetachedHead(path)
+	branch, errBranch := executor.GetCurrentBranch(path)
+	if detached || errBranch != nil {
+		remote, err = getRemote(path)
+	} else {
+		remote, err = getRemoteForBranch(path, branch)
+	}
+	if err != nil {
+		exitOnError("Sorry, I can't get the remote 😢", err)
+	}
+	return remote
+}
+
+// Fetch a remote and print the remote-tracking branches that changed
+func fetchRemote(path string, remote executor.Remote, prune bool, profileLocal profile.Profile) {
+	before, err := executor.ListRemoteBranches(path, remote.Name)
+	if err != nil {
+		exitOnError("Sorry, I can't list the branches of "+remote.Name+" 😢", err)
+	}
+
+	err = runWithProgress("Fetching "+remote.Name, func(ctx context.Context) error {
+		return executor.Fetch(ctx, path, remote.Name, prune, profileLocal.Username, profileLocal.Password)
+	})
+	if isAuthError(err) {
+		print.Message("Uh oh, your credentials are wrong 😢. Please select another profile.", print.Error)
+		fetchRemote(path, remote, prune, getSyncProfile(path, true))
+		return
+	} else if err == git.NoErrAlreadyUpToDate {
+		print.Message("%s is already up to date", print.Success, remote.Name)
+		return
+	} else if err != nil {
+		exitOnError("Sorry, I can't fetch "+remote.Name+" 😢", err)
+	}
+
+	after, err := executor.ListRemoteBranches(path, remote.Name)
+	if err != nil {
+		exitOnError("Sorry, I can't list the branches of "+remote.Name+" 😢", err)
+	}
+	printRemoteBranchesChanges(remote.Name, before, after)
+	print.Message("I've fetched %s 🎉", print.Success, remote.Name)
+}
+
+func printRemoteBranchesChanges(remote string, before map[string]string, after map[string]string) {
+	var names []string
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldHash, existedBefore := before[name]
+		newHash, existsAfter := after[name]
+		switch {
+		case !existedBefore:
+			fmt.Fprintf(color.Output, "\t%s %s/%s\n", color.GreenString("+ new"), remote, name)
+		case !existsAfter:
+			fmt.Fprintf(color.Output, "\t%s %s/%s\n", color.RedString("- pruned"), remote, name)
+		case oldHash != newHash:
+			fmt.Fprintf(color.Output, "\t%s %s/%s %s\n", color.YellowString("~ updated"), remote, name, color.HiBlackString(oldHash[:7]+".."+newHash[:7]))
+		}
+	}
+}
@@ -1,7 +1,15 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
@@ -22,6 +30,7 @@ func Fix(cmd *cobra.Command, args []string) {
 		"3) I want to discard changes since last commit",
 		"4) I want to go back to a previous commit",
 		"5) I forgot to add a change in the last commit",
+		"6) I put too much in one commit",
 		"Cancel",
 	}
 
@@ -39,10 +48,10 @@ func Fix(cmd *cobra.Command, args []string) {
 	case "1) I've made a typo in the last commit message":
 		amendMessage(wd)
 	case "2) I've committed on the wrong branch":
-		cherryPick()
+		moveCommits(wd)
 
 	case "3) I want to discard changes since last commit":
-		print.Message("I have a command for that: gut undo", print.Info)
+		print.Message("I have a command for that: gut reset", print.Info)
 
 	case "4) I want to go back to a previous commit":
 		print.Message("I have a command for that: gut revert", print.Info)
@@ -50,6 +59,9 @@ func Fix(cmd *cobra.Command, args []string) {
 	case "5) I forgot to add a change in the last commit":
 		amendCommit(wd)
 
+	case "6) I put too much in one commit":
+		splitCommit(wd)
+
 	case "Cancel":
 
 	default:
@@ -70,6 +82,8 @@ func amendMessage(path string) {
 		exitOnError("Sorry, I can't find Git on your computer", nil)
 	}
 
+	protectionTrailer := checkCurrentBranchProtection(path, actionRewrite)
+
 	// Get head commit
 	head, err := executor.GetHeadHash(path)
 	if err != nil {
@@ -92,16 +106,16 @@ func amendMessage(path string) {
 	}
 
 	// Check if commit has been pushed
+	// Changing it rewrites the history, so we ask for a confirmation
 	contains := executor.GitRemoteContainsHash(head)
-	if contains {
-		exitOnError("Sorry, I can't change the last commit message because it has been sync with the remote\nIt might break other people's work", nil)
-
+	if contains && !confirmRewritePushedCommits() {
+		return
 	}
 	// Get the new commit message
 
 	print.Message("\nLet's write the new commit message", print.None)
 
-	message := promptCommitMessage("", "")
+	message := addCommitTrailer(promptCommitMessage("", ""), protectionTrailer)
 
 	// Prompt a confirmation
 	res, err := prompt.InputBool("Are you sure you want me to change the last commit message?", true)
@@ -121,17 +135,474 @@ func amendMessage(path string) {
 
 }
 
-func cherryPick() {
-	print.Message("This feature is not implemented yet", print.Error)
-	print.Message("However, you can do it manually with gut on a brand new branch", print.None)
-	print.Message(`	
-	# Switch to a new branch with the actual commit
-	gut switch [new branch name]
-	# Go back to the branch you want to fix
-	gut switch [branch name]
-	# Revert the state to an old commit
-	gut revert 
-	`, print.None)
+// Maximum number of commits offered when moving or rewriting commits
+const maxUnpushedCommits = 30
+
+// List the last commits of the current branch that aren't pushed yet, newest first
+//
+// Pushed commits are in the history of the remote, so they aren't listed. The list also stops
+// before the first merge commit and the first commit of the repository, as they can't be rewritten
+func listUnpushedCommits(path string) []object.Commit {
+	commits, err := executor.ListCommit(path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the commits", err)
+	}
+	if pushed := getIndexLatestCommitPushed(commits); pushed != -1 {
+		commits = commits[:pushed]
+	}
+	if len(commits) > maxUnpushedCommits {
+		commits = commits[:maxUnpushedCommits]
+	}
+	for i, commit := range commits {
+		if len(commit.ParentHashes) != 1 || (i+1 < len(commits) && commit.ParentHashes[0] != commits[i+1].Hash) {
+			return commits[:i]
+		}
+	}
+	return commits
+}
+
+// Work out how to move the selected commits of a branch to another one
+//
+// commits are the last commits of the branch, newest first. It returns the commit the branch is reset to,
+// the commits to move and the commits to apply again on the branch after the reset (both oldest first)
+//
+// The history must be linear from HEAD to the oldest commit selected, and the oldest one must have a parent
+func planCommitMove(commits []object.Commit, selected map[string]bool) (string, []string, []string, error) {
+	oldest := -1
+	for i, commit := range commits {
+		if selected[commit.Hash.String()] {
+			oldest = i
+		}
+	}
+	if oldest == -1 {
+		return "", nil, nil, errors.New("no commit is selected")
+	}
+	for i := 0; i <= oldest; i++ {
+		if len(commits[i].ParentHashes) != 1 {
+			if len(commits[i].ParentHashes) == 0 {
+				return "", nil, nil, errors.New("the first commit of the repository can't be moved")
+			}
+			return "", nil, nil, fmt.Errorf("%s is a merge commit, I can only move commits made after it", commits[i].Hash.String()[:7])
+		}
+		if i < oldest && commits[i].ParentHashes[0] != commits[i+1].Hash {
+			return "", nil, nil, errors.New("the history of the branch isn't linear")
+		}
+	}
+	var move, replay []string
+	for i := oldest; i >= 0; i-- {
+		if selected[commits[i].Hash.String()] {
+			move = append(move, commits[i].Hash.String())
+		} else {
+			replay = append(replay, commits[i].Hash.String())
+		}
+	}
+	return commits[oldest].ParentHashes[0].String(), move, replay, nil
+}
+
+// Ask the user which commits were made on the wrong branch (newest first)
+func chooseCommitsToMove(commits []object.Commit) map[string]bool {
+	choices := make([]string, len(commits))
+	for i, commit := range commits {
+		choices[i] = fmt.Sprintf("%s %s (%s)", color.HiYellowString(commit.Hash.String()[:7]), getTitleFromCommit(commit.Message), commit.Author.When.Format("Mon Jan 2 15:04:05"))
+	}
+	var answers []int
+	err := survey.AskOne(&survey.MultiSelect{
+		Message: "Which commits are on the wrong branch?",
+		Options: choices,
+		Default: []int{0},
+	}, &answers, survey.WithValidator(survey.Required))
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	selected := map[string]bool{}
+	for _, i := range answers {
+		selected[commits[i].Hash.String()] = true
+	}
+	return selected
+}
+
+// Ask the user where the commits belong. A new branch starts from base (or from the base of the naming convention)
+//
+// Return the branch and true if it has been created
+func chooseTargetBranch(path string, current string, base string) (string, bool) {
+	branches, err := executor.ListBranches(path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the branches 😢", err)
+	}
+	const newBranch = "A new branch"
+	options := []string{newBranch}
+	for _, branch := range branches {
+		if branch != current {
+			options = append(options, branch)
+		}
+	}
+	target, err := prompt.InputSelect("On which branch should they be?", options)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if target != newBranch {
+		return target, false
+	}
+
+	name, err := prompt.InputLine("Name of the new branch: ")
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	name = enforceBranchConvention(path, strings.TrimSpace(name))
+	if err := executor.ValidateBranchName(name); err != nil {
+		exitOnError(name+" isn't a valid branch name", err)
+	}
+	exists, err := executor.CheckIfBranchExists(path, name)
+	if err != nil {
+		exitOnError("I can't check if the branch exists", err)
+	}
+	if exists {
+		print.Message("The branch %s already exists, I'll move the commits to it", print.Info, name)
+		return name, false
+	}
+	if conventionBase := getNewBranchBase(path); conventionBase != "" {
+		err = executor.CreateBranchFrom(path, name, conventionBase)
+	} else {
+		err = executor.CreateBranchAt(path, name, base)
+	}
+	if err != nil {
+		exitOnError("My bad, I can't create the branch "+name, err)
+	}
+	return name, true
+}
+
+// Commits to move from a branch to another one, worked out by planCommitMove
+type commitMove struct {
+	branch string
+	target string
+	// True if target has been created for the move, so it's deleted if the move fails
+	created bool
+	// Tip of branch before the move
+	head string
+	// Commit branch is reset to
+	base string
+	// Commits cherry-picked on target, oldest first
+	commits []string
+	// Commits applied again on branch after the reset, oldest first
+	replay []string
+}
+
+// Cherry-pick the commits on the target, then remove them from the current branch
+//
+// Until it's done, the previous tip of the branch is kept in refs/gut/recovery/<branch>.
+// If something fails, both branches are restored: the target is put back at its previous tip, or deleted if it was created
+func applyCommitMove(path string, move commitMove, branchTrailer string, targetTrailer string) {
+	recoveryRef := "refs/gut/recovery/" + move.branch
+	err := executor.GitUpdateRef(recoveryRef, move.head, "gut fix: move commits to "+move.target)
+	if err != nil {
+		exitOnError("Sorry, I can't save the current state of "+move.branch+", so I haven't changed anything 😢", err)
+	}
+	// Tip of the target before the commits are copied on it. Empty until it's known
+	var targetTip string
+	// Go back to both branches as they were, and remove what was created
+	restore := func() error {
+		if executor.IsCherryPicking(path) {
+			executor.GitCherryPickAbort()
+		}
+		current, _ := executor.GetCurrentBranch(path)
+		if current != move.branch {
+			executor.CheckoutBranch(path, move.branch)
+		}
+		err := executor.GitResetHard(recoveryRef)
+		if move.created {
+			executor.DeleteBranch(path, move.target)
+		} else if targetTip != "" {
+			executor.GitUpdateRef("refs/heads/"+move.target, targetTip, "gut fix: cancel the move from "+move.branch)
+		}
+		return err
+	}
+
+	/* ------------------------- Copy the commits on the target ------------------------ */
+	err = executor.CheckoutBranch(path, move.target)
+	if err == nil {
+		targetTip, err = executor.GetHeadHash(path)
+	}
+	if err != nil {
+		restore()
+		exitOnError("Sorry, I can't switch to "+move.target+" 😢", err)
+	}
+	err = executor.GitCherryPick(move.commits...)
+	if err != nil {
+		restore()
+		executor.GitDeleteRef(recoveryRef)
+		exitOnError("The commits don't apply on "+move.target+" (they might conflict with its changes), so I haven't changed anything", err)
+	}
+	recordProtectionOverride(path, targetTrailer)
+
+	/* ----------------------- Remove them from the current branch ---------------------- */
+	err = executor.CheckoutBranch(path, move.branch)
+	if err == nil {
+		err = executor.GitResetHard(move.base)
+	}
+	if err == nil && len(move.replay) > 0 {
+		err = executor.GitCherryPick(move.replay...)
+	}
+	if err != nil {
+		if restoreErr := restore(); restoreErr != nil {
+			exitOnError("I can't remove the commits from "+move.branch+", nor restore it. Its previous state is kept in "+recoveryRef+". Restore it with git reset --hard "+recoveryRef, err)
+		}
+		executor.GitDeleteRef(recoveryRef)
+		exitOnError("I can't remove the commits from "+move.branch+" because the commits after them depend on them, so I haven't changed anything", err)
+	}
+	if len(move.replay) > 0 {
+		// The commits applied again are rewritten, so the override is recorded in the last one
+		recordProtectionOverride(path, branchTrailer)
+	}
+	executor.GitDeleteRef(recoveryRef)
+}
+
+// Move commits that aren't pushed yet from the current branch to another one
+//
+// The commits are cherry-picked on the target, then removed from the current branch.
+// Until it's done, the previous tip of the branch is kept in refs/gut/recovery/<branch>
+func moveCommits(path string) {
+	checkIfGitInstalled()
+	verifUserConfig(path)
+	branch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	clean, err := executor.IsWorkTreeClean(path)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommitted changes", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+	protectionTrailer := checkCurrentBranchProtection(path, actionRewrite)
+
+	commits := listUnpushedCommits(path)
+	if len(commits) == 0 {
+		print.Message("All the commits of %s are pushed, so I can't move them. Use gut revert to undo them", print.Info, branch)
+		return
+	}
+
+	selected := chooseCommitsToMove(commits)
+	base, move, replay, err := planCommitMove(commits, selected)
+	if err != nil {
+		exitOnError("Sorry, I can't move these commits", err)
+	}
+	head := commits[0].Hash.String()
+
+	target, created := chooseTargetBranch(path, branch, base)
+	targetTrailer := checkBranchProtection(path, target, actionSave)
+	res, err := prompt.InputBool(fmt.Sprintf("I'll move %d commit(s) from %s to %s. Continue?", len(move), branch, target), true)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		if created {
+			executor.DeleteBranch(path, target)
+		}
+		return
+	}
+
+	applyCommitMove(path, commitMove{
+		branch:  branch,
+		target:  target,
+		created: created,
+		head:    head,
+		base:    base,
+		commits: move,
+		replay:  replay,
+	}, protectionTrailer, targetTrailer)
+	print.Message("I've moved %d commit(s) from %s to %s 🎉", print.Success, len(move), branch, target)
+
+	res, err = prompt.InputBool("Do you want to switch to "+target+"?", true)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if res {
+		err = executor.CheckoutBranch(path, target)
+		if err != nil {
+			exitOnError("I can't switch to the branch "+target, err)
+		}
+		recordCheckout(path, branch, target)
+		print.Message("I switched to the branch %s", print.Info, target)
+	}
+}
+
+// Return the hashes of the commits made after a commit, oldest first
+//
+// commits are the last commits of the branch, newest first. Return nil if the commit isn't in them
+func commitsAfter(commits []object.Commit, hash plumbing.Hash) []string {
+	for i := range commits {
+		if commits[i].Hash != hash {
+			continue
+		}
+		var after []string
+		for j := i - 1; j >= 0; j-- {
+			after = append(after, commits[j].Hash.String())
+		}
+		return after
+	}
+	return nil
+}
+
+// Split a commit that isn't pushed yet into several commits
+//
+// The commit is undone but its changes are kept in the working tree. The user then saves them file by file
+// or hunk by hunk, and the commits made after it are applied again.
+// Until it's done, the previous tip of the branch is kept in refs/gut/recovery/<branch>
+func splitCommit(path string) {
+	checkIfGitInstalled()
+	verifUserConfig(path)
+	branch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	clean, err := executor.IsWorkTreeClean(path)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommitted changes", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+	protectionTrailer := checkCurrentBranchProtection(path, actionRewrite)
+
+	commits := listUnpushedCommits(path)
+	if len(commits) == 0 {
+		print.Message("All the commits of %s are pushed, so I can't split them", print.Info, branch)
+		return
+	}
+	print.Message("Which commit do you want to split?", print.None)
+	commit := chooseCommit(commits)
+	hash := commit.Hash.String()
+	replay := commitsAfter(commits, commit.Hash)
+
+	recoveryRef := "refs/gut/recovery/" + branch
+	err = executor.GitUpdateRef(recoveryRef, commits[0].Hash.String(), "gut fix: split "+hash[:7])
+	if err != nil {
+		exitOnError("Sorry, I can't save the current state of "+branch+", so I haven't changed anything 😢", err)
+	}
+	restore := func() {
+		if executor.IsCherryPicking(path) {
+			executor.GitCherryPickAbort()
+		}
+		err := executor.GitResetHard(recoveryRef)
+		if err != nil {
+			exitOnError("Sorry, I can't restore "+branch+". Its previous state is kept in "+recoveryRef+". Restore it with git reset --hard "+recoveryRef, err)
+		}
+		executor.GitDeleteRef(recoveryRef)
+	}
+
+	/* ------------------------- Undo the commit but keep its changes ------------------------ */
+	err = executor.GitResetHard(hash)
+	if err == nil {
+		err = executor.GitResetMixed(commit.ParentHashes[0].String())
+	}
+	if err == nil {
+		err = executor.GitAddIntentToAdd()
+	}
+	if err != nil {
+		restore()
+		exitOnError("Sorry, I can't undo the commit, so I haven't changed anything 😢", err)
+	}
+	print.Message("I've undone %s. Its changes are back in your working tree: let's save them in several commits", print.Info, hash[:7])
+	print.Message("If anything goes wrong, restore the branch with git reset --hard %s", print.Optional, recoveryRef)
+
+	/* ---------------------------- Save the changes in several commits --------------------------- */
+	const (
+		byFile = "Choose the files of the next commit"
+		byHunk = "Choose the changes (hunk by hunk) of the next commit"
+		rest   = "Save all the remaining changes in the next commit"
+		cancel = "Cancel and restore the commit"
+	)
+	made := 0
+	for {
+		files, err := executor.GitListUnstagedFiles()
+		if err != nil {
+			restore()
+			exitOnError("Sorry, I can't list the changes left, so I've restored the branch 😢", err)
+		}
+		if len(files) == 0 {
+			break
+		}
+		print.Message("\n%d file(s) left to save:", print.None, len(files))
+		for _, file := range files {
+			fmt.Fprintf(color.Output, "\t%s\n", color.YellowString(file))
+		}
+		res, err := prompt.InputSelect(fmt.Sprintf("How do you want to make commit %d?", made+1), []string{byFile, byHunk, rest, cancel})
+		if err != nil {
+			restore()
+			exitOnKnownError(errorReadInput, err)
+		}
+		switch res {
+		case byFile:
+			var answers []int
+			err = survey.AskOne(&survey.MultiSelect{
+				Message: "Which files go in this commit?",
+				Options: files,
+			}, &answers, survey.WithValidator(survey.Required))
+			if err != nil {
+				restore()
+				exitOnKnownError(errorReadInput, err)
+			}
+			for _, i := range answers {
+				err = executor.GitAddFile(files[i])
+				if err != nil {
+					restore()
+					exitOnError("Sorry, I can't add "+files[i]+", so I've restored the branch 😢", err)
+				}
+			}
+		case byHunk:
+			err = executor.GitAddPatch()
+			if err != nil {
+				print.Message("I can't choose the changes this way, try by file 😓", print.Error)
+			}
+		case rest:
+			for _, file := range files {
+				err = executor.GitAddFile(file)
+				if err != nil {
+					restore()
+					exitOnError("Sorry, I can't add "+file+", so I've restored the branch 😢", err)
+				}
+			}
+		case cancel:
+			restore()
+			print.Message("Okay, I've restored the commit", print.Info)
+			return
+		}
+
+		staged, err := executor.GitHasStagedChanges()
+		if err != nil {
+			restore()
+			exitOnError("Sorry, I can't check the changes you chose, so I've restored the branch 😢", err)
+		}
+		if !staged {
+			print.Message("You haven't chosen any change", print.Warning)
+			continue
+		}
+		print.Message("Let's write the message of commit %d. The message of the commit split was: %s", print.None, made+1, getTitleFromCommit(commit.Message))
+		err = executor.GitCommit(promptCommitMessage("", ""))
+		if err != nil {
+			restore()
+			exitOnError("Sorry, I can't commit, so I've restored the branch 😢", err)
+		}
+		made++
+	}
+	if made == 0 {
+		restore()
+		print.Message("%s has no change to split, so I've left it as is", print.Info, hash[:7])
+		return
+	}
+
+	/* ----------------------------- Apply the next commits again ----------------------------- */
+	if len(replay) > 0 {
+		err = executor.GitCherryPick(replay...)
+		if err != nil {
+			restore()
+			exitOnError("The commits after "+hash[:7]+" don't apply on the new commits, so I've restored the branch", err)
+		}
+	}
+	recordProtectionOverride(path, protectionTrailer)
+	executor.GitDeleteRef(recoveryRef)
+	print.Message("I've split %s into %d commits 🎉", print.Success, hash[:7], made)
 }
 
 func amendCommit(path string) {
@@ -141,6 +612,8 @@ func amendCommit(path string) {
 		exitOnError("Sorry, I can't find Git on your computer", nil)
 	}
 
+	protectionTrailer := checkCurrentBranchProtection(path, actionRewrite)
+
 	// Get head commit
 	head, err := executor.GetHeadHash(path)
 	if err != nil {
@@ -148,10 +621,10 @@ func amendCommit(path string) {
 	}
 
 	// Check if commit has been pushed
+	// Changing it rewrites the history, so we ask for a confirmation
 	contains := executor.GitRemoteContainsHash(head)
-	if contains {
-		exitOnError("Sorry, I can't change the last commit content because it has been sync with the remote\nIt might break other people's work", nil)
-
+	if contains && !confirmRewritePushedCommits() {
+		return
 	}
 
 	// Add all files
@@ -184,6 +657,7 @@ func amendCommit(path string) {
 	if err != nil {
 		exitOnError("Sorry, I can't amend the last commit", err)
 	}
+	recordProtectionOverride(path, protectionTrailer)
 	print.Message("I've successfully changed the last commit content", print.Success)
 
 }
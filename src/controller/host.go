@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/prompt"
+	"github.com/julien040/gut/src/provider"
+)
+
+// Return the provider of a host, or nil if gut doesn't know which website it is
+//
+// Hosts in the hosts config take precedence over the public websites
+func getProvider(host string) (provider.Provider, error) {
+	if host == "" {
+		return nil, nil
+	}
+	conf, found, err := profile.GetHost(host)
+	if err != nil {
+		return nil, fmt.Errorf("I can't read the hosts config: %w", err)
+	}
+	if found {
+		return provider.New(conf.Provider, conf.Host, conf.API)
+	}
+	kind := provider.KnownKind(host)
+	if kind == "" {
+		return nil, nil
+	}
+	return provider.New(kind, host, "")
+}
+
+// Return the credentials of a profile to use with a provider
+func getCredentials(profileLocal profile.Profile) provider.Credentials {
+	return provider.Credentials{Username: profileLocal.Username, Password: profileLocal.Password}
+}
+
+// List the self-hosted websites gut knows
+func Host(cmd *cobra.Command, args []string) {
+	hosts, err := profile.GetHosts()
+	if err != nil {
+		exitOnError("Sorry, I can't read the hosts config 😢", err)
+	}
+	if len(hosts) == 0 {
+		print.Message("No self-hosted website is configured. Add one with gut host add", print.Info)
+		return
+	}
+	for _, conf := range hosts {
+		api := conf.API
+		if api == "" {
+			api = provider.DefaultAPI(conf.Provider, conf.Host)
+		}
+		fmt.Printf("%s %s %s\n", color.New(color.Bold).Sprint(conf.Host), conf.Provider, color.HiBlackString(api))
+	}
+}
+
+// Map a self-hosted website to a provider and the base URL of its API
+func HostAdd(cmd *cobra.Command, args []string) {
+	var host string
+	if len(args) > 0 {
+		host = args[0]
+	} else {
+		err := survey.AskOne(&survey.Input{
+			Message: "Host of the website (e.g. git.company.com):",
+		}, &host, survey.WithValidator(survey.Required))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	// The user might paste a URL instead of a host
+	if parsed := getHost(host); parsed != "" {
+		host = parsed
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "/"))
+
+	kind, _ := cmd.Flags().GetString("provider")
+	if kind == "" {
+		var err error
+		kind, err = prompt.InputSelect("Which software runs on "+host+"?", provider.Kinds)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	apiURL, _ := cmd.Flags().GetString("api")
+	if !cmd.Flags().Changed("api") {
+		err := survey.AskOne(&survey.Input{
+			Message: "Base URL of the API:",
+			Default: provider.DefaultAPI(strings.ToLower(kind), host),
+		}, &apiURL, survey.WithValidator(survey.Required))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	// Check the config before saving it
+	_, err := provider.New(kind, host, apiURL)
+	if err != nil {
+		exitOnError("Sorry, I can't use this config 😢", err)
+	}
+	if apiURL == provider.DefaultAPI(strings.ToLower(kind), host) {
+		apiURL = ""
+	}
+	conf := profile.HostConf{Host: host, Provider: strings.ToLower(kind), API: apiURL}
+
+	hosts, err := profile.GetHosts()
+	if err != nil {
+		exitOnError("Sorry, I can't read the hosts config 😢", err)
+	}
+	replaced := false
+	for i := range hosts {
+		if strings.EqualFold(hosts[i].Host, host) {
+			hosts[i] = conf
+			replaced = true
+		}
+	}
+	if !replaced {
+		hosts = append(hosts, conf)
+	}
+	err = profile.SaveHosts(hosts)
+	if err != nil {
+		exitOnError("Sorry, I can't save the hosts config 😢", err)
+	}
+	print.Message("I'll use the API of %s for the repositories hosted on %s 🎉", print.Success, conf.Provider, host)
+}
+
+// Forget a self-hosted website
+func HostRemove(cmd *cobra.Command, args []string) {
+	hosts, err := profile.GetHosts()
+	if err != nil {
+		exitOnError("Sorry, I can't read the hosts config 😢", err)
+	}
+	if len(hosts) == 0 {
+		print.Message("No self-hosted website is configured", print.Info)
+		return
+	}
+
+	var host string
+	if len(args) > 0 {
+		host = args[0]
+	} else {
+		names := make([]string, len(hosts))
+		for i, conf := range hosts {
+			names[i] = conf.Host
+		}
+		host, err = prompt.InputSelect("Which host do you want to remove?", names)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+
+	var kept []profile.HostConf
+	for _, conf := range hosts {
+		if !strings.EqualFold(conf.Host, host) {
+			kept = append(kept, conf)
+		}
+	}
+	if len(kept) == len(hosts) {
+		print.Message("%s isn't configured", print.Error, host)
+		os.Exit(1)
+	}
+	err = profile.SaveHosts(kept)
+	if err != nil {
+		exitOnError("Sorry, I can't save the hosts config 😢", err)
+	}
+	print.Message("I've removed %s", print.Success, host)
+}
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// The operation being run, recorded in the journal once it's done
+var journalCurrent *executor.JournalEntry
+
+// Wrap the handler of a command that changes the repository, so that it's recorded in the journal
+// and can be undone with gut undo
+//
+// The operation is also recorded when the command exits early because of an error
+func Journaled(run func(cmd *cobra.Command, args []string)) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		startJournal(cmd, args)
+		run(cmd, args)
+		recordJournal()
+	}
+}
+
+// Save the state of the repository before the command runs
+func startJournal(cmd *cobra.Command, args []string) {
+	wd, err := os.Getwd()
+	if err != nil || !executor.IsPathGitRepo(wd) || !executor.IsGitInstalled() {
+		return
+	}
+	before, err := executor.GetRepoState(wd)
+	if err != nil {
+		return
+	}
+	command := cmd.CommandPath()
+	if len(args) > 0 {
+		command += " " + strings.Join(args, " ")
+	}
+	journalCurrent = &executor.JournalEntry{Command: command, Before: before}
+}
+
+// Record the operation in the journal if it changed the repository
+//
+// It never exits: a journal that can't be written mustn't make the command fail
+func recordJournal() {
+	entry := journalCurrent
+	journalCurrent = nil
+	if entry == nil {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	after, err := executor.GetRepoState(wd)
+	if err != nil || len(describeStateChange(entry.Before, after)) == 0 {
+		return
+	}
+	entry.After = after
+	entry.When = time.Now()
+	executor.AppendJournal(wd, *entry)
+}
+
+// Describe what changes when the repository goes from a state to another, one line per change
+func describeStateChange(from executor.RepoState, to executor.RepoState) []string {
+	var changes []string
+	if from.Head != to.Head || (from.Head == "" && from.HeadHash != to.HeadHash) {
+		switch {
+		case to.Head != "":
+			changes = append(changes, "switch to "+refDisplayName(to.Head))
+		case to.HeadHash != "":
+			changes = append(changes, "switch to the commit "+shortHash(to.HeadHash))
+		}
+	}
+
+	refs := map[string]bool{}
+	for ref := range from.Refs {
+		refs[ref] = true
+	}
+	for ref := range to.Refs {
+		refs[ref] = true
+	}
+	sorted := make([]string, 0, len(refs))
+	for ref := range refs {
+		sorted = append(sorted, ref)
+	}
+	sort.Strings(sorted)
+	for _, ref := range sorted {
+		oldHash, newHash := from.Refs[ref], to.Refs[ref]
+		name := refDisplayName(ref)
+		switch {
+		case oldHash == newHash:
+		case oldHash == "":
+			changes = append(changes, fmt.Sprintf("create %s at %s", name, shortHash(newHash)))
+		case newHash == "":
+			changes = append(changes, fmt.Sprintf("delete %s (at %s)", name, shortHash(oldHash)))
+		default:
+			changes = append(changes, fmt.Sprintf("move %s from %s to %s", name, shortHash(oldHash), shortHash(newHash)))
+		}
+	}
+
+	if from.Index != to.Index && to.Index != "" {
+		changes = append(changes, "change the staged files")
+	}
+	if from.Worktree != to.Worktree && to.Worktree != "" {
+		changes = append(changes, "change the files of the working tree")
+	}
+	return changes
+}
+
+// Return the state without the stash, which gut undo doesn't restore (see executor.StashRef)
+func withoutStash(state executor.RepoState) executor.RepoState {
+	refs := make(map[string]string, len(state.Refs))
+	for ref, hash := range state.Refs {
+		if ref != executor.StashRef {
+			refs[ref] = hash
+		}
+	}
+	state.Refs = refs
+	return state
+}
+
+// Check the protection rules of the branches that going from a state to another deletes or rewrites
+//
+// A branch that only moves forward isn't rewritten. Exit if a rule forbids it, unless --override-protection is set
+func checkUndoProtection(wd string, from executor.RepoState, to executor.RepoState) {
+	refs := make([]string, 0, len(from.Refs))
+	for ref := range from.Refs {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		branch, found := strings.CutPrefix(ref, "refs/heads/")
+		if !found {
+			continue
+		}
+		hash := from.Refs[ref]
+		target, exists := to.Refs[ref]
+		switch {
+		case !exists:
+			checkBranchProtection(wd, branch, actionDelete)
+		case target != hash:
+			if forward, err := executor.IsAncestor(wd, hash, target); err != nil || !forward {
+				checkBranchProtection(wd, branch, actionRewrite)
+			}
+		}
+	}
+}
+
+// Name of a ref for the user (e.g. the branch main for refs/heads/main)
+func refDisplayName(ref string) string {
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		return "the branch " + strings.TrimPrefix(ref, "refs/heads/")
+	case strings.HasPrefix(ref, "refs/tags/"):
+		return "the tag " + strings.TrimPrefix(ref, "refs/tags/")
+	case ref == "refs/stash":
+		return "the stash"
+	case strings.HasPrefix(ref, "refs/gut/autostash/"):
+		return "the changes kept for " + strings.TrimPrefix(ref, "refs/gut/autostash/")
+	}
+	return ref
+}
+
+// Undo an operation of gut by putting the repository back in the state it was before it
+//
+// Without --last, the user picks the operation in the journal. Undoing an operation also undoes the ones made after it
+func UndoOperation(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+
+	entries, err := executor.ReadJournal(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the journal of gut 😢", err)
+	}
+	if len(entries) == 0 {
+		print.Message("I haven't recorded any operation in this repository yet, so there is nothing to undo", print.Info)
+		print.Message("To discard your uncommitted changes, use gut reset", print.Optional)
+		return
+	}
+
+	// Newest first
+	index := len(entries) - 1
+	if last, _ := cmd.Flags().GetBool("last"); !last {
+		now := time.Now()
+		options := make([]string, len(entries))
+		for i := range entries {
+			entry := entries[len(entries)-1-i]
+			options[i] = fmt.Sprintf("%s %s", color.HiCyanString(entry.Command), color.HiBlackString(formatAge(entry.When, now)))
+		}
+		var answer int
+		err = survey.AskOne(&survey.Select{
+			Message:  "Which operation do you want to undo? The ones after it are undone too",
+			Options:  options,
+			PageSize: 15,
+		}, &answer)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		index = len(entries) - 1 - answer
+	}
+	entry := entries[index]
+
+	current, err := executor.GetRepoState(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the state of the repository 😢", err)
+	}
+	stashChanged := current.Refs[executor.StashRef] != entry.Before.Refs[executor.StashRef]
+	current, before, after := withoutStash(current), withoutStash(entry.Before), withoutStash(entry.After)
+	changes := describeStateChange(current, before)
+	if len(changes) == 0 {
+		print.Message("The repository is already as it was before %s", print.Info, entry.Command)
+		return
+	}
+	print.Message("To undo %s (%s), I'll:", print.Info, entry.Command, formatAge(entry.When, time.Now()))
+	for _, change := range changes {
+		fmt.Fprintf(color.Output, "\t- %s\n", change)
+	}
+	if later := len(entries) - 1 - index; later > 0 {
+		print.Message("This also undoes the %d operation(s) made after it", print.Warning, later)
+	} else if len(describeStateChange(after, current)) > 0 {
+		print.Message("The repository has changed since, outside of gut. These changes are undone too", print.Warning)
+	}
+	if stashChanged {
+		print.Message("The stash isn't undone, so that none of its entries is lost. Manage it with gut stash", print.Optional)
+	}
+	checkUndoProtection(wd, current, before)
+
+	clean, err := executor.IsWorkTreeClean(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommitted changes", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+	res, err := prompt.InputBool("Do you want to continue?", true)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't undo anything", print.Info)
+		return
+	}
+
+	err = executor.RestoreRepoState(wd, before)
+	if err != nil {
+		exitOnError("Sorry, I can't restore the repository. Run gut undo again to pick the state to restore 😢", err)
+	}
+	print.Message("I've undone %s 🎉", print.Success, entry.Command)
+}
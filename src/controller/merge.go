@@ -3,12 +3,13 @@
 import (
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
 	"github.com/julien040/gut/src/prompt"
+	"github.com/julien040/gut/src/provider"
 	"github.com/spf13/cobra"
 )
 
@@ -21,6 +22,20 @@ func Merge(cmd *cobra.Command, args []string) {
 
 	checkIfDetachedHead(wd)
 
+	// A merge stopped because of conflicts is resumed
+	if executor.IsMerging(wd) {
+		checkIfGitInstalled()
+		print.Message("A merge is in progress", print.Info)
+		head, err := executor.GetHeadHash(wd)
+		if err != nil {
+			exitOnError("Sorry, I can't get the last commit 😢", err)
+		}
+		if resolveMergeConflicts(wd, head) {
+			print.Message("I've merged the branches 🎉", print.Success)
+		}
+		return
+	}
+
 	// Check if working directory is clean
 	clean, err := executor.IsWorkTreeClean(wd)
 	if err != nil {
@@ -106,35 +121,26 @@ func Merge(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Check if the repo is using only one origin. If yes, we act accordingly to each platform
-	// If not, returns an empty string. In this case, we use the default merge method
-	platform, remoteURL, err := getPlatformUsed(wd)
+	// Check if the repo is using only one origin. If yes, we act accordingly to the website hosting it
+	// If not, the provider is nil. In this case, we use the default merge method
+	hosting, repoPath, err := getPlatformUsed(wd)
 	if err != nil {
-		print.Message("I can't know if you use Github, Gitlab or Bitbucket 😢. I will use the default merge method. Check the message below to know why I can't know.", print.Warning)
+		print.Message("I can't know which website hosts your repository 😢. I will use the default merge method. Check the message below to know why I can't know.", print.Warning)
 		print.Message(err.Error(), print.Warning)
 	}
 
-	// Return the URL to open a pull request on the platform
-	switch platform {
-	case "github.com":
-		githubURL := remoteURL + "/compare/" + currentBranch + "..." + branch + "?quick_pull=1"
-		promptUserToSync()
-		color.Black("To merge %s into %s, I recommend opening a pull request on GitHub. Open the following URL in your browser:\n%s\n", branch, currentBranch, color.WhiteString(githubURL))
-		openInBrowser(githubURL)
-	case "gitlab.com":
-		gitlabURL := remoteURL + "/merge_requests/new?merge_request%5Bsource_branch%5D=" + branch + "&merge_request%5Btarget_branch%5D=" + currentBranch
+	// Return the URL to open a pull request on the website
+	switch {
+	case hosting != nil:
+		compareURL := hosting.CompareURL(repoPath, currentBranch, branch)
 		promptUserToSync()
-		color.Black("To merge %s into %s, I recommend opening a merge request on GitLab. Open the following URL in your browser:\n%s\n", branch, currentBranch, color.WhiteString(gitlabURL))
-		openInBrowser(gitlabURL)
-	case "bitbucket.org":
-		bitbucketURL := remoteURL + "/pull-requests/new?source=" + branch + "&dest=" + currentBranch
-		promptUserToSync()
-		color.Black("To merge %s into %s, I recommend opening a pull request on Bitbucket. Open the following URL in your browser:\n%s\n", branch, currentBranch, color.WhiteString(bitbucketURL))
-		openInBrowser(bitbucketURL)
+		color.Black("To merge %s into %s, I recommend opening a %s on %s. Open the following URL in your browser:\n%s\n", branch, currentBranch, hosting.PullRequestName(), hosting.Name(), color.WhiteString(compareURL))
+		print.Message("You can also open it from the terminal with gut pr create", print.Optional)
+		openInBrowser(compareURL)
 	default:
 		isGitInstalled := executor.IsGitInstalled()
 		if isGitInstalled {
-			color.Black("To merge %s into %s, I recommend using the following command:\n%s\n", branch, currentBranch, color.WhiteString("git merge "+branch))
+			mergeLocally(wd, currentBranch, branch)
 		} else {
 			exitOnError("You are using a platform that I don't support yet and you don't have git installed. I can't help you sorry 😢. Please install git (https://git-scm.com/downloads) and try again.", nil)
 		}
@@ -143,30 +149,192 @@ func Merge(cmd *cobra.Command, args []string) {
 
 }
 
-// Get the remote host of a git repository
+// Get the website hosting a git repository
 //
 // Response:
-// The host of the remote | the remote URL without the .git suffix | an error if there is one
+// The provider of the website | the path of the repository on it (e.g. julien040/gut) | an error if there is one
 //
-// ⚠️ Note: If there is more than one remote, or none, we can't know so we return an empty string
-func getPlatformUsed(path string) (string, string, error) {
+// ⚠️ Note: If there is more than one remote, or none, or if the website isn't known, the provider is nil
+func getPlatformUsed(path string) (provider.Provider, string, error) {
+	// Get the remotes
+	remotes, err := executor.ListRemote(path)
+	if err != nil {
+		return nil, "", err
+	}
+	// We can only know the platform if there is only one remote
+	if len(remotes) != 1 {
+		return nil, "", nil
+	}
+	repoPath := getRepoPath(remotes[0].Url)
+	if repoPath == "" {
+		return nil, "", nil
+	}
+	hosting, err := getProvider(getHost(remotes[0].Url))
+	return hosting, repoPath, err
+}
+
+// Maximum number of commits listed in the message of a merge commit
+const maxCommitsInMergeMessage = 20
 
-	removeDotGit := func(url string) string {
-		if strings.HasSuffix(url, ".git") {
-			return url[:len(url)-4]
+// Write the message of a merge commit, with the titles of the commits merged
+func mergeCommitMessage(branch string, into string, commits []object.Commit) string {
+	message := "🔀 Merge " + branch + " into " + into + "\n"
+	for i, commit := range commits {
+		if i == maxCommitsInMergeMessage {
+			message += fmt.Sprintf("\n- and %d more", len(commits)-i)
+			break
 		}
-		return url
+		message += "\n- " + getTitleFromCommit(commit.Message)
 	}
+	return message
+}
 
-	// Get the remotes
-	remotes, err := executor.ListRemote(path)
+// Merge the branch into the current branch without going through a pull request
+//
+// The incoming commits and the files with conflicts are shown first. A fast-forward is used when possible
+func mergeLocally(wd string, currentBranch string, branch string) {
+	protectionTrailer := checkBranchProtection(wd, currentBranch, actionSave)
+	head, err := executor.GetHeadHash(wd)
 	if err != nil {
-		return "", "", err
+		exitOnError("Sorry, I can't get the last commit 😢", err)
 	}
-	// We can only know the platform if there is only one remote
-	if len(remotes) == 1 {
-		return getHost(remotes[0].Url), removeDotGit(remotes[0].Url), nil
+	branchCommit, err := executor.GetBranchCommit(wd, branch)
+	if err != nil {
+		exitOnError("Sorry, I can't get the last commit of "+branch+" 😢", err)
+	}
+	incoming, err := executor.ListCommitsNotIn(wd, branchCommit.Hash.String(), head)
+	if err != nil {
+		exitOnError("Sorry, I can't list the commits to merge 😢", err)
+	}
+	if len(incoming) == 0 {
+		print.Message("%s is already merged into %s", print.Success, branch, currentBranch)
+		return
+	}
+
+	/* --------------------------------- Preview -------------------------------- */
+	print.Message("%d commit(s) of %s will be merged into %s:", print.None, len(incoming), branch, currentBranch)
+	printCommitList(incoming)
+	fastForward, err := executor.IsAncestor(wd, head, branchCommit.Hash.String())
+	if err != nil {
+		exitOnError("Sorry, I can't compare the branches 😢", err)
+	}
+	if fastForward {
+		print.Message("There is no new commit on %s, so I'll just move it forward", print.Info, currentBranch)
+	} else if conflicts, err := executor.GitMergePreview(branch); err == nil {
+		// The preview needs a recent version of git, so it's skipped if it fails
+		if len(conflicts) == 0 {
+			print.Message("There should be no conflict", print.Info)
+		} else {
+			print.Message("These files will have conflicts:", print.Warning)
+			for _, file := range conflicts {
+				fmt.Fprintf(color.Output, "\t%s\n", color.RedString(file))
+			}
+		}
+	}
+	res, err := prompt.InputBool("Do you want to merge "+branch+" into "+currentBranch+"?", true)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't merge the branches", print.Info)
+		return
+	}
+
+	/* ---------------------------------- Merge --------------------------------- */
+	if fastForward {
+		err = executor.GitMergeFastForward(branch)
+		if err != nil {
+			exitOnError("Sorry, I can't move "+currentBranch+" forward 😢", err)
+		}
+	} else {
+		message := addCommitTrailer(mergeCommitMessage(branch, currentBranch, incoming), protectionTrailer)
+		err = executor.GitMerge(branch, message)
+		if err != nil {
+			if !executor.IsMerging(wd) {
+				exitOnError("Sorry, I can't merge the branches 😢", err)
+			}
+			print.Message("Some changes of %s and %s conflict. Let's fix them together", print.Warning, branch, currentBranch)
+			if !resolveMergeConflicts(wd, head) {
+				return
+			}
+		}
+	}
+	syncSubmodules(wd, getRepoProfileIfAny(wd))
+	print.Message("I've merged %s into %s 🎉", print.Success, branch, currentBranch)
+}
+
+// Guide the user through the conflicts of the merge in progress, then create the merge commit
+//
+// Return false if the merge is aborted, in which case HEAD is reset to origHead, or postponed
+func resolveMergeConflicts(wd string, origHead string) bool {
+	const (
+		continueMerge = "I've fixed the conflicts, continue the merge"
+		keepOurs      = "Keep my version of a file"
+		takeTheirs    = "Take the incoming version of a file"
+		abortMerge    = "Abort the merge and go back to where I was"
+		later         = "Stop here, I'll fix them later"
+	)
+	for {
+		conflicts, err := executor.GitListConflicts()
+		if err != nil {
+			exitOnError("Sorry, I can't list the files with conflicts 😢", err)
+		}
+		if len(conflicts) == 0 {
+			err = executor.GitMergeContinue()
+			if err != nil {
+				exitOnError("Sorry, I can't create the merge commit 😢", err)
+			}
+			return true
+		}
+
+		print.Message("\nThese files have conflicts:", print.None)
+		for _, file := range conflicts {
+			fmt.Fprintf(color.Output, "\t%s\n", color.RedString(file))
+		}
+		print.Message("Open them in your editor and keep the right changes between <<<<<<< and >>>>>>>", print.Optional)
+		res, err := prompt.InputSelect("What do you want to do?", []string{continueMerge, keepOurs, takeTheirs, abortMerge, later})
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+
+		switch res {
+		case continueMerge:
+			for _, file := range conflicts {
+				markers, err := executor.HasConflictMarkers(wd, file)
+				if err != nil {
+					exitOnError("Sorry, I can't read "+file+" 😢", err)
+				}
+				if markers {
+					print.Message("%s still has conflict markers", print.Warning, file)
+					continue
+				}
+				err = executor.GitAddFile(file)
+				if err != nil {
+					exitOnError("Sorry, I can't mark "+file+" as resolved 😢", err)
+				}
+			}
+		case keepOurs, takeTheirs:
+			file, err := prompt.InputSelect("Which file?", conflicts)
+			if err != nil {
+				exitOnKnownError(errorReadInput, err)
+			}
+			err = executor.GitResolveWith(file, res == keepOurs)
+			if err != nil {
+				print.Message("I can't resolve %s this way, fix it in your editor 😓", print.Error, file)
+			}
+		case abortMerge:
+			err = executor.GitMergeAbort()
+			if head, _ := executor.GetHeadHash(wd); err != nil || head != origHead {
+				err = executor.GitResetHard(origHead)
+				if err != nil {
+					exitOnError("Sorry, I can't abort the merge 😢", err)
+				}
+			}
+			print.Message("I've aborted the merge. Everything is back as before", print.Success)
+			return false
+		case later:
+			print.Message("Okay. Run gut merge again when you're ready to continue or abort the merge", print.Info)
+			return false
+		}
 	}
-	// If there is more, or none, we can't know so we return an empty string
-	return "", "", nil
 }
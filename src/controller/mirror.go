@@ -0,0 +1,351 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// Result of the push to one of the sync targets
+type mirrorPushResult struct {
+	Target   profile.SyncTarget
+	Remote   executor.Remote
+	Profile  profile.Profile
+	Duration time.Duration
+	UpToDate bool
+	Err      error
+}
+
+func isAuthError(err error) bool {
+	return err == transport.ErrAuthorizationFailed || err == transport.ErrAuthenticationRequired
+}
+
+// Find a remote by its name in a list of remotes
+func findRemote(remotes []executor.Remote, name string) (executor.Remote, bool) {
+	for _, remote := range remotes {
+		if remote.Name == name {
+			return remote, true
+		}
+	}
+	return executor.Remote{}, false
+}
+
+// Return the profile to use for a sync target
+//
+// If the target has no profile, or the profile doesn't exist anymore, defaultProfile is used
+func getTargetProfile(target profile.SyncTarget, defaultProfile profile.Profile) profile.Profile {
+	if target.ProfileID == "" {
+		return defaultProfile
+	}
+	profileTarget, err := profile.GetProfileByID(target.ProfileID)
+	if err != nil {
+		return defaultProfile
+	}
+	return profileTarget
+}
+
+// Sync a repository mirrored on several remotes
+//
+// The current branch is pulled from the primary remote, then pushed concurrently to every sync target
+func syncMirrors(path string, conf profile.SyncConf) {
+	remotes, err := executor.ListRemote(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the remotes of the repository 😢", err)
+	}
+	primary, pullProfile := getPrimaryRemote(path, conf, remotes)
+
+	branch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	// Checked before pulling, so that a protected branch is still pulled
+	if isPushProtected(path, branch) {
+		pullAndPublish(path, primary, pullProfile, true, printPushProtected)
+		return
+	}
+	pullAndPublish(path, primary, pullProfile, false, func(path string, branch string, profileLocal profile.Profile) {
+		pushToMirrors(path, branch, primary.Name, conf.Push, remotes, profileLocal)
+	})
+}
+
+// Return the remote to pull from and the profile to use with it
+func getPrimaryRemote(path string, conf profile.SyncConf, remotes []executor.Remote) (executor.Remote, profile.Profile) {
+	primary, ok := findRemote(remotes, conf.Pull)
+	if !ok {
+		exitOnError("The remote "+conf.Pull+" I should pull from doesn't exist anymore. Run gut sync setup to choose another one", nil)
+	}
+
+	// Get the profile to pull from the primary remote
+	// The profile of the path is only used (and asked if missing) when no profile is set for the remote
+	profileID := conf.PullProfileID
+	if profileID == "" {
+		for _, target := range conf.Push {
+			if target.Remote == primary.Name {
+				profileID = target.ProfileID
+			}
+		}
+	}
+	pullProfile, err := profile.GetProfileByID(profileID)
+	if profileID == "" || err != nil {
+		pullProfile = getSyncProfile(path, false)
+	}
+
+	return primary, pullProfile
+}
+
+// Get the profile to use with a remote
+//
+// If the remote is a sync target, we use its profile. Otherwise, we use the profile of the path
+func getRemoteProfile(path string, remote string) profile.Profile {
+	conf, err := profile.GetGutConf(path)
+	if err == nil {
+		for _, target := range conf.Sync.Push {
+			if target.Remote != remote || target.ProfileID == "" {
+				continue
+			}
+			profileTarget, err := profile.GetProfileByID(target.ProfileID)
+			if err == nil {
+				return profileTarget
+			}
+		}
+	}
+	return getSyncProfile(path, false)
+}
+
+// Push the branch to every sync target at the same time and print a summary
+//
+// The upstream of the branch is only set on the primary remote.
+// When the credentials of a target are wrong, the user is asked to select another profile once all the pushes are done
+func pushToMirrors(path string, branch string, primary string, targets []profile.SyncTarget, remotes []executor.Remote, defaultProfile profile.Profile) {
+	results := make([]mirrorPushResult, len(targets))
+	for i, target := range targets {
+		results[i].Target = target
+		results[i].Profile = getTargetProfile(target, defaultProfile)
+		remote, ok := findRemote(remotes, target.Remote)
+		if !ok {
+			results[i].Err = fmt.Errorf("the remote %s doesn't exist", target.Remote)
+		}
+		results[i].Remote = remote
+	}
+
+	pushTarget := func(ctx context.Context, result *mirrorPushResult) {
+		start := time.Now()
+		var err error
+		if result.Remote.Name == primary {
+			err = executor.Push(ctx, path, result.Remote.Name, branch, result.Profile.Username, result.Profile.Password)
+		} else {
+			err = executor.PushMirror(ctx, path, result.Remote.Name, branch, result.Profile.Username, result.Profile.Password)
+		}
+		result.Duration = time.Since(start)
+		result.UpToDate = err == git.NoErrAlreadyUpToDate
+		if result.UpToDate {
+			err = nil
+		}
+		result.Err = err
+	}
+
+	var pending []*mirrorPushResult
+	var labels []string
+	for i := range results {
+		if results[i].Err == nil {
+			pending = append(pending, &results[i])
+			labels = append(labels, results[i].Remote.Name)
+		}
+	}
+	fmt.Fprintf(color.Output, "%s Pushing %s to %s\n", color.HiBlackString("→"), branch, strings.Join(labels, ", "))
+	runAllWithProgress(labels, func(ctx context.Context, i int) error {
+		pushTarget(ctx, pending[i])
+		return pending[i].Err
+	}, func(i int, err error) {
+		result := pending[i]
+		result.Err = err
+		if err != nil {
+			fmt.Fprintf(color.Output, "%s %s: %s\n", color.RedString("✗"), result.Remote.Name, color.RedString(err.Error()))
+		} else {
+			fmt.Fprintf(color.Output, "%s %s %s\n", color.GreenString("✓"), result.Remote.Name, color.HiBlackString("(%s)", result.Duration.Round(time.Millisecond)))
+		}
+	})
+
+	// Prompts can't be shown concurrently, so we retry the targets with wrong credentials one by one
+	// The user doesn't want to be asked anything after Ctrl-C
+	cancelled := false
+	for i := range results {
+		cancelled = cancelled || errors.Is(results[i].Err, errTransferCancelled)
+	}
+	for i := range results {
+		result := &results[i]
+		for isAuthError(result.Err) && !cancelled {
+			print.Message("Uh oh, your credentials are wrong for %s 😢. Please select another profile.", print.Error, result.Remote.Name)
+			result.Profile = selectProfile(result.Remote.Url, true)
+			saveSyncTargetProfile(path, result.Target.Remote, result.Profile)
+			result.Err = runWithProgress("Pushing "+branch+" to "+result.Remote.Name, func(ctx context.Context) error {
+				pushTarget(ctx, result)
+				return result.Err
+			})
+			cancelled = errors.Is(result.Err, errTransferCancelled)
+		}
+	}
+
+	printMirrorPushResults(results)
+}
+
+// Remember the profile to use for a sync target
+func saveSyncTargetProfile(path string, remote string, profileTarget profile.Profile) {
+	conf, err := profile.GetGutConf(path)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	for i := range conf.Sync.Push {
+		if conf.Sync.Push[i].Remote == remote {
+			conf.Sync.Push[i].ProfileID = profileTarget.Id
+		}
+	}
+	err = profile.SaveGutConf(path, conf)
+	if err != nil {
+		exitOnError("Sorry, I can't save the .gut file 😢", err)
+	}
+}
+
+func printMirrorPushResults(results []mirrorPushResult) {
+	fmt.Println()
+	fmt.Fprintln(color.Output, color.HiBlackString("Remote | Profile | Result"))
+	failed := 0
+	for _, result := range results {
+		var status string
+		switch {
+		case result.Err != nil:
+			failed++
+			status = color.RedString("✗ " + result.Err.Error())
+		case result.UpToDate:
+			status = color.GreenString("✓ already up to date")
+		default:
+			status = color.GreenString("✓ pushed")
+		}
+		fmt.Fprintf(color.Output, "%s | %s | %s\n", color.HiBlueString(result.Target.Remote), result.Profile.Alias, status)
+	}
+	fmt.Println()
+	if failed == 0 {
+		print.Message("I've successfully synced your repository with %d remotes 🎉", print.Success, len(results))
+	} else {
+		print.Message("I couldn't push to %d of your %d remotes", print.Error, failed, len(results))
+		os.Exit(1)
+	}
+}
+
+// Configure the remotes gut sync pulls from and pushes to
+func SyncSetup(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+
+	// If targets are already set, the user might want to remove them
+	if len(conf.Sync.Push) > 0 {
+		print.Message("I pull from %s and push to:", print.None, conf.Sync.Pull)
+		for _, target := range conf.Sync.Push {
+			fmt.Fprintf(color.Output, "\t%s %s\n", target.Remote, color.HiBlackString(getTargetProfile(target, profile.Profile{}).Alias))
+		}
+		const (
+			change = "Change the remotes"
+			remove = "Stop mirroring (sync with only one remote)"
+			cancel = "Cancel"
+		)
+		res, err := prompt.InputSelect("What do you want to do?", []string{change, remove, cancel})
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		switch res {
+		case remove:
+			conf.Sync = profile.SyncConf{}
+			err = profile.SaveGutConf(wd, conf)
+			if err != nil {
+				exitOnError("Sorry, I can't save the .gut file 😢", err)
+			}
+			print.Message("Okay, gut sync will only use one remote from now on", print.Success)
+			return
+		case cancel:
+			return
+		}
+	}
+
+	remotes, err := executor.ListRemote(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the remotes of the repository 😢", err)
+	}
+	if len(remotes) == 0 {
+		print.Message("You don't have any remote yet. Let's add one", print.Info)
+		remote, err := addRemote(wd, true)
+		if err != nil {
+			exitOnError("Sorry, I can't add the remote", err)
+		}
+		remotes = append(remotes, remote)
+	}
+	remoteNames := make([]string, len(remotes))
+	for i, r := range remotes {
+		remoteNames[i] = r.Name + " <" + r.Url + ">"
+	}
+
+	// Choose the remote to pull from
+	var pullIndex int
+	err = survey.AskOne(&survey.Select{
+		Message: "Which remote do you want to pull from?",
+		Options: remoteNames,
+	}, &pullIndex)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+
+	// Choose the remotes to push to
+	var pushIndexes []int
+	err = survey.AskOne(&survey.MultiSelect{
+		Message: "Which remotes do you want to push to?",
+		Options: remoteNames,
+		Default: remoteNames,
+	}, &pushIndexes, survey.WithValidator(survey.Required))
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+
+	newConf := profile.SyncConf{Pull: remotes[pullIndex].Name}
+	pullIsTarget := false
+	for _, i := range pushIndexes {
+		print.Message("Which profile do you want to use for %s <%s>?", print.Info, remotes[i].Name, remotes[i].Url)
+		profileTarget := selectProfile(remotes[i].Url, true)
+		newConf.Push = append(newConf.Push, profile.SyncTarget{
+			Remote:    remotes[i].Name,
+			ProfileID: profileTarget.Id,
+		})
+		if i == pullIndex {
+			pullIsTarget = true
+		}
+	}
+	// We also need a profile to pull if we don't push to this remote
+	if !pullIsTarget {
+		print.Message("Which profile do you want to use to pull from %s <%s>?", print.Info, remotes[pullIndex].Name, remotes[pullIndex].Url)
+		newConf.PullProfileID = selectProfile(remotes[pullIndex].Url, true).Id
+	}
+
+	conf.Sync = newConf
+	err = profile.SaveGutConf(wd, conf)
+	if err != nil {
+		exitOnError("Sorry, I can't save the .gut file 😢", err)
+	}
+	print.Message("All set! gut sync will now pull from %s and push to %d remotes 🎉", print.Success, newConf.Pull, len(newConf.Push))
+}
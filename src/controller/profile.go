@@ -10,7 +10,6 @@
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/BurntSushi/toml"
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	promptui "github.com/manifoldco/promptui"
@@ -346,39 +345,16 @@ func associateProfileToPath(profileArg profile.Profile, path string) {
 	// Set Profile Data in git config
 	executor.SetUserConfig(path, profileArg.Username, profileArg.Email)
 
-	// Get current date
-	currentDate := time.Now().Format("2006-01-02 15:04:05")
-
-	// Check if file exists
+	// Read the .gut file to keep the other settings of the repository
 	pathToWrite := filepath.Join(path, ".gut")
-	if _, err := os.Stat(pathToWrite); os.IsNotExist(err) {
-		// Create file
-		f, err := os.Create(pathToWrite)
-		if err != nil {
-			exitOnError("I can't create the file .gut at "+pathToWrite, err)
-		}
-		f.Close()
-	}
-
-	// Open file in write mode
-	f, err := os.OpenFile(pathToWrite,
-		os.O_WRONLY|os.O_TRUNC, 0755)
+	conf, err := profile.GetGutConf(path)
 	if err != nil {
-		exitOnError("I can't open the file .gut at "+pathToWrite, err)
-	}
-
-	// Close file at the end of the function
-	defer f.Close()
-
-	// Create the schema
-	profileIDSchema := profile.SchemaGutConf{
-		ProfileID: profileArg.Id,
-		UpdatedAt: currentDate,
+		exitOnError("I can't read the file .gut at "+pathToWrite, err)
 	}
 
-	// Encode ID in TOML and write it in .gut file
-	t := toml.NewEncoder(f)
-	err = t.Encode(profileIDSchema)
+	// Write the profile ID in the .gut file
+	conf.ProfileID = profileArg.Id
+	err = profile.SaveGutConf(path, conf)
 	if err != nil {
 		exitOnError("I can't write the profile ID in the file .gut at "+pathToWrite, err)
 	}
@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+)
+
+// When stdout is not a terminal (CI, pipe), we print a log line at this interval
+const progressLogInterval = 5 * time.Second
+
+// Frames of the spinner shown before the progress
+var progressFrames = spinner.CharSets[9]
+
+func isStdoutTerminal() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// Format a number of bytes for humans (e.g. 1.2 MiB)
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for bytes >= 1024 && i < len(units)-1 {
+		bytes /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[i])
+}
+
+// Describe the statistics of a transfer in one line
+//
+// e.g. "Compressing objects 45% (45/100) · 1.2 MiB · 300.0 KiB/s"
+func formatTransferStats(stats executor.TransferStats) string {
+	var parts []string
+	switch {
+	case stats.Resolving && stats.ObjectsTotal > 0:
+		parts = append(parts, fmt.Sprintf("Resolving %d objects", stats.ObjectsTotal))
+	case stats.Stage != "" && stats.Total > 0 && stats.Percent < 100:
+		parts = append(parts, fmt.Sprintf("%s %d%% (%d/%d)", stats.Stage, stats.Percent, stats.Current, stats.Total))
+	case stats.Stage != "":
+		parts = append(parts, stats.Stage)
+	}
+	if !stats.Resolving && stats.ObjectsTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%d objects", stats.ObjectsTotal))
+	}
+	transferred := stats.BytesReceived + stats.BytesSent
+	if transferred > 0 {
+		parts = append(parts, formatBytes(float64(transferred)), formatBytes(stats.Throughput())+"/s")
+	}
+	if len(parts) == 0 {
+		return "Connecting..."
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Return a context cancelled when the user presses Ctrl-C
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// Returned by runWithProgress when the user cancels the transfer with Ctrl-C
+var errTransferCancelled = errors.New("the transfer has been cancelled")
+
+// Return errTransferCancelled if the operation failed because it has been cancelled with Ctrl-C
+func cancelledError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return errTransferCancelled
+	}
+	return err
+}
+
+// Exit if the transfer has been cancelled with Ctrl-C
+//
+// For the callers that go on after a failed transfer. exitOnError already handles the cancellation
+func exitIfCancelled(err error) {
+	if errors.Is(err, errTransferCancelled) {
+		exitOnError("", err)
+	}
+}
+
+// Run a network operation (clone, fetch, pull, push) while showing its progress
+//
+// On a terminal, the progress is updated in place. Otherwise, a log line is printed every few seconds.
+// Ctrl-C cancels the context given to the operation, which aborts the transfer.
+// errTransferCancelled is then returned so that the caller can clean up before exiting
+func runWithProgress(label string, operation func(ctx context.Context) error) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	progress := executor.NewTransferProgress()
+	ctx = executor.WithTransferProgress(ctx, progress)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- operation(ctx)
+	}()
+
+	tty := isStdoutTerminal()
+	interval := 100 * time.Millisecond
+	if !tty {
+		interval = progressLogInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	frame := 0
+	for {
+		select {
+		case err := <-done:
+			if tty {
+				fmt.Print("\r\033[K")
+			}
+			if err = cancelledError(ctx, err); err == errTransferCancelled {
+				return err
+			}
+			printTransferSummary(label, progress.Stats())
+			return err
+		case <-ticker.C:
+			line := formatTransferStats(progress.Stats())
+			if tty {
+				fmt.Fprintf(color.Output, "\r\033[K%s %s %s", label, progressFrames[frame%len(progressFrames)], color.HiBlackString(line))
+				frame++
+			} else {
+				fmt.Printf("%s: %s\n", label, line)
+			}
+		}
+	}
+}
+
+// Run several network operations at the same time, each one with its own progress
+//
+// onDone is called when an operation finishes, with errTransferCancelled after Ctrl-C. Calls to onDone never overlap,
+// and it can print lines without messing with the progress
+func runAllWithProgress(labels []string, operation func(ctx context.Context, i int) error, onDone func(i int, err error)) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	progresses := make([]*executor.TransferProgress, len(labels))
+	finished := make([]bool, len(labels))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	tty := isStdoutTerminal()
+	clearLine := func() {
+		if tty {
+			fmt.Print("\r\033[K")
+		}
+	}
+
+	for i := range labels {
+		progresses[i] = executor.NewTransferProgress()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := operation(executor.WithTransferProgress(ctx, progresses[i]), i)
+			lock.Lock()
+			defer lock.Unlock()
+			finished[i] = true
+			clearLine()
+			onDone(i, cancelledError(ctx, err))
+		}(i)
+	}
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+
+	interval := 100 * time.Millisecond
+	if !tty {
+		interval = progressLogInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	frame := 0
+	for {
+		select {
+		case <-allDone:
+			return
+		case <-ticker.C:
+			lock.Lock()
+			var running []string
+			for i, label := range labels {
+				if finished[i] {
+					continue
+				}
+				line := formatTransferStats(progresses[i].Stats())
+				if tty {
+					running = append(running, label+" "+color.HiBlackString(line))
+				} else {
+					fmt.Printf("%s: %s\n", label, line)
+				}
+			}
+			if tty && len(running) > 0 {
+				fmt.Fprintf(color.Output, "\r\033[K%s %s", progressFrames[frame%len(progressFrames)], strings.Join(running, " | "))
+				frame++
+			}
+			lock.Unlock()
+		}
+	}
+}
+
+// Print what has been transferred when the transfer took some time
+func printTransferSummary(label string, stats executor.TransferStats) {
+	transferred := stats.BytesReceived + stats.BytesSent
+	if stats.Elapsed < 2*time.Second || transferred == 0 {
+		return
+	}
+	summary := fmt.Sprintf("%s: %s in %s (%s/s)", label, formatBytes(float64(transferred)), stats.Elapsed.Round(time.Second), formatBytes(stats.Throughput()))
+	if stats.ObjectsTotal > 0 {
+		summary += fmt.Sprintf(", %d objects", stats.ObjectsTotal)
+	}
+	print.Message(summary, print.Optional)
+}
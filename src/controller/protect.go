@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// Something gut does to a branch that a protection rule can forbid
+type protectedAction string
+
+const (
+	actionSave    protectedAction = "save"
+	actionRewrite protectedAction = "rewrite"
+	actionDelete  protectedAction = "delete"
+	actionPush    protectedAction = "push"
+)
+
+// Trailer added to the commits created while overriding a protection rule
+const protectionOverrideTrailer = "Protection-Override"
+
+// Set by the --override-protection flag of every command
+var protectionOverridden bool
+
+// Allow the operations forbidden by the protection rules for this run
+//
+// Called before the command runs with the value of --override-protection
+func SetProtectionOverride(override bool) {
+	protectionOverridden = override
+}
+
+// Return true if the rule forbids the action
+func (action protectedAction) forbiddenBy(rule profile.ProtectRule) bool {
+	switch action {
+	case actionSave:
+		return rule.NoSave
+	case actionRewrite:
+		return rule.NoRewrite
+	case actionDelete:
+		return rule.NoDelete
+	case actionPush:
+		return rule.PullRequestOnly
+	}
+	return false
+}
+
+// Explain why the action is forbidden on the branch
+func (action protectedAction) explain(branch string) string {
+	switch action {
+	case actionSave:
+		return branch + " is protected: you can't save changes directly on it"
+	case actionRewrite:
+		return branch + " is protected: its history can't be rewritten"
+	case actionDelete:
+		return branch + " is protected: it can't be deleted or renamed"
+	case actionPush:
+		return branch + " is protected: changes must go through a pull request"
+	}
+	return branch + " is protected"
+}
+
+// Return true if the branch name matches the pattern of a rule (e.g. release/* matches release/1.0)
+func matchProtectPattern(pattern string, branch string) bool {
+	if pattern == branch {
+		return true
+	}
+	matched, err := path.Match(pattern, branch)
+	return err == nil && matched
+}
+
+// Return the first rule of the .gut file that forbids the action on the branch
+func findProtectionRule(wd string, branch string, action protectedAction) (profile.ProtectRule, bool) {
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	for _, rule := range conf.Protect {
+		if matchProtectPattern(rule.Pattern, branch) && action.forbiddenBy(rule) {
+			return rule, true
+		}
+	}
+	return profile.ProtectRule{}, false
+}
+
+// Exit if a protection rule forbids the action on the branch
+//
+// With --override-protection, a warning is printed instead and the trailer
+// to record in the commit message is returned. Otherwise, the trailer is empty
+func checkBranchProtection(wd string, branch string, action protectedAction) string {
+	rule, found := findProtectionRule(wd, branch, action)
+	if !found {
+		return ""
+	}
+	if protectionOverridden {
+		print.Message("⚠️  %s. I'll do it anyway because of --override-protection", print.Warning, action.explain(branch))
+		return fmt.Sprintf("%s: %s (%s)", protectionOverrideTrailer, action, rule.Pattern)
+	}
+	print.Message(action.explain(branch)+" (rule "+rule.Pattern+" in .gut)", print.Error)
+	if action == actionPush {
+		print.Message("Push your changes to another branch and open a pull request with gut merge", print.Optional)
+	}
+	print.Message("If you really need to, run the command again with --override-protection", print.Optional)
+	os.Exit(1)
+	return ""
+}
+
+// Same as checkBranchProtection for the branch checked out
+func checkCurrentBranchProtection(wd string, action protectedAction) string {
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	return checkBranchProtection(wd, branch, action)
+}
+
+// Check that the changes can be saved on the current branch
+//
+// If the branch is protected, we offer to move the changes to a new branch.
+// Return the trailer to add to the commit message (see checkBranchProtection)
+func checkSaveProtection(wd string) string {
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	_, found := findProtectionRule(wd, branch, actionSave)
+	if !found || protectionOverridden {
+		return checkBranchProtection(wd, branch, actionSave)
+	}
+
+	print.Message(actionSave.explain(branch), print.Warning)
+	const (
+		moveChanges = "Move my changes to a new branch and save them there"
+		cancel      = "Cancel"
+	)
+	res, err := prompt.InputSelect("What do you want to do?", []string{moveChanges, cancel})
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if res == cancel {
+		print.Message("Okay, I won't save your changes", print.Info)
+		os.Exit(0)
+	}
+
+	newBranch, err := prompt.InputLine("Name of the new branch: ")
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if executor.ValidateBranchName(newBranch) != nil {
+		exitOnError(newBranch+" isn't a valid branch name", nil)
+	}
+	newBranch = enforceBranchConvention(wd, newBranch)
+	// The uncommitted changes are kept when the branch is created
+	err = executor.CreateBranch(wd, newBranch)
+	if err != nil {
+		exitOnError("Sorry, I can't create the branch "+newBranch+" 😢", err)
+	}
+	recordCheckout(wd, branch, newBranch)
+	print.Message("You are now on the branch %s with your changes", print.Success, newBranch)
+	return ""
+}
+
+// Add a trailer at the end of a commit message
+func addCommitTrailer(message string, trailer string) string {
+	if trailer == "" {
+		return message
+	}
+	return strings.TrimRight(message, "\n") + "\n\n" + trailer
+}
+
+// Amend the last commit to record the override of a protection rule
+func recordProtectionOverride(wd string, trailer string) {
+	if trailer == "" {
+		return
+	}
+	head, err := executor.GetHeadHash(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the last commit 😢", err)
+	}
+	commit, err := executor.GetCommitByHash(wd, head)
+	if err != nil {
+		exitOnError("Sorry, I can't get the last commit 😢", err)
+	}
+	err = executor.GitCommitAmend(addCommitTrailer(commit.Message, trailer))
+	if err != nil {
+		exitOnError("Sorry, I can't record the override in the last commit 😢", err)
+	}
+}
+
+// List the protection rules of the repository
+func Protect(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	if len(conf.Protect) == 0 {
+		print.Message("No branch is protected. Protect one with gut protect add", print.Info)
+		return
+	}
+	print.Message("Protected branches:", print.None)
+	for _, rule := range conf.Protect {
+		fmt.Fprintf(color.Output, "\t%s %s\n", color.HiBlueString(rule.Pattern), color.HiBlackString(describeProtectRule(rule)))
+	}
+}
+
+// Describe what a rule forbids (e.g. no save, no delete)
+func describeProtectRule(rule profile.ProtectRule) string {
+	var forbidden []string
+	if rule.NoSave {
+		forbidden = append(forbidden, "no save")
+	}
+	if rule.NoRewrite {
+		forbidden = append(forbidden, "no rewrite")
+	}
+	if rule.NoDelete {
+		forbidden = append(forbidden, "no delete")
+	}
+	if rule.PullRequestOnly {
+		forbidden = append(forbidden, "pull requests only")
+	}
+	return strings.Join(forbidden, ", ")
+}
+
+// Add or replace a protection rule
+func ProtectAdd(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+
+	var pattern string
+	if len(args) > 0 {
+		pattern = args[0]
+	} else {
+		defaultBranch, err := executor.GetDefaultBranch(wd)
+		if err != nil {
+			exitOnError("I can't find the default branch", err)
+		}
+		err = survey.AskOne(&survey.Input{
+			Message: "Branch to protect (you can use * e.g. release/*):",
+			Default: defaultBranch,
+		}, &pattern, survey.WithValidator(survey.Required))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		exitOnError(pattern+" isn't a valid pattern", err)
+	}
+
+	const (
+		noSave    = "Forbid saving directly on it"
+		noRewrite = "Forbid rewriting its history"
+		noDelete  = "Forbid deleting or renaming it"
+		prOnly    = "Changes only through pull requests (forbid pushing to it)"
+	)
+	policies := []string{noSave, noRewrite, noDelete, prOnly}
+	var selected []string
+	err := survey.AskOne(&survey.MultiSelect{
+		Message: "What do you want to forbid on " + pattern + "?",
+		Options: policies,
+		Default: policies,
+	}, &selected, survey.WithValidator(survey.Required))
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	rule := profile.ProtectRule{Pattern: pattern}
+	for _, policy := range selected {
+		switch policy {
+		case noSave:
+			rule.NoSave = true
+		case noRewrite:
+			rule.NoRewrite = true
+		case noDelete:
+			rule.NoDelete = true
+		case prOnly:
+			rule.PullRequestOnly = true
+		}
+	}
+
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	replaced := false
+	for i := range conf.Protect {
+		if conf.Protect[i].Pattern == pattern {
+			conf.Protect[i] = rule
+			replaced = true
+		}
+	}
+	if !replaced {
+		conf.Protect = append(conf.Protect, rule)
+	}
+	err = profile.SaveGutConf(wd, conf)
+	if err != nil {
+		exitOnError("Sorry, I can't save the .gut file 😢", err)
+	}
+	print.Message("I've protected %s (%s) 🔒", print.Success, pattern, describeProtectRule(rule))
+}
+
+// Remove a protection rule
+func ProtectRemove(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	if len(conf.Protect) == 0 {
+		print.Message("No branch is protected", print.Info)
+		return
+	}
+
+	var pattern string
+	if len(args) > 0 {
+		pattern = args[0]
+	} else {
+		patterns := make([]string, len(conf.Protect))
+		for i, rule := range conf.Protect {
+			patterns[i] = rule.Pattern
+		}
+		pattern, err = prompt.InputSelect("Which rule do you want to remove?", patterns)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+
+	var kept []profile.ProtectRule
+	for _, rule := range conf.Protect {
+		if rule.Pattern != pattern {
+			kept = append(kept, rule)
+		}
+	}
+	if len(kept) == len(conf.Protect) {
+		print.Message("There is no rule for %s", print.Error, pattern)
+		os.Exit(1)
+	}
+	conf.Protect = kept
+	err = profile.SaveGutConf(wd, conf)
+	if err != nil {
+		exitOnError("Sorry, I can't save the .gut file 😢", err)
+	}
+	print.Message("%s is not protected anymore", print.Success, pattern)
+}
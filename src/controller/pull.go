@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+)
+
+// Pull the current branch without pushing
+func Pull(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfDetachedHead(wd)
+
+	// Integrating the remote changes needs a clean working tree
+	clean, err := executor.IsWorkTreeClean(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommited changes 😢", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+
+	var remote executor.Remote
+	var profileLocal profile.Profile
+
+	// If the repository is mirrored, we pull from the primary remote
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	if len(conf.Sync.Push) > 0 {
+		remotes, err := executor.ListRemote(wd)
+		if err != nil {
+			exitOnError("Sorry, I can't get the remotes of the repository 😢", err)
+		}
+		remote, profileLocal = getPrimaryRemote(wd, conf.Sync, remotes)
+	} else {
+		remote = getCurrentRemote(wd)
+		profileLocal = getRemoteProfile(wd, remote.Name)
+	}
+
+	pullAndPublish(wd, remote, profileLocal, true, func(path string, branch string, profileLocal profile.Profile) {
+		print.Message("To publish your commits, run gut push", print.Optional)
+	})
+}
@@ -0,0 +1,386 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/prompt"
+	"github.com/julien040/gut/src/provider"
+)
+
+// Turn a branch name into a title (e.g. feat/add-login-page => Add login page)
+func humanizeBranchName(branch string) string {
+	if i := strings.LastIndex(branch, "/"); i != -1 {
+		branch = branch[i+1:]
+	}
+	title := strings.Join(strings.FieldsFunc(branch, func(r rune) bool {
+		return r == '-' || r == '_'
+	}), " ")
+	runes := []rune(title)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+// Write the title and the body of a pull request from the commits of the branch (newest first)
+//
+// With one commit, its message is used. Otherwise, the title comes from the branch name and the body lists the commits
+func pullRequestFromCommits(branch string, commits []object.Commit) (string, string) {
+	if len(commits) == 1 {
+		title, body, _ := strings.Cut(strings.TrimSpace(commits[0].Message), "\n")
+		return title, strings.TrimSpace(body)
+	}
+	var body []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		body = append(body, "- "+getTitleFromCommit(commits[i].Message))
+	}
+	return humanizeBranchName(branch), strings.Join(body, "\n")
+}
+
+// The repository on the website hosting the remote of the current branch
+type hostedRepo struct {
+	wd      string
+	remote  executor.Remote
+	hosting provider.Provider
+	// Path of the repository on the website (e.g. julien040/gut)
+	path string
+}
+
+// Find the website hosting the remote of the current branch, or exit if gut doesn't know it
+func getHostedRepo(wd string) hostedRepo {
+	remote := getCurrentRemote(wd)
+	host := getHost(remote.Url)
+	hosting, err := getProvider(host)
+	if err != nil {
+		exitOnError("Sorry, I can't find which website hosts the repository 😢", err)
+	}
+	if hosting == nil {
+		exitOnError("I don't know which website "+host+" is. If it's a GitHub Enterprise, GitLab or Gitea instance, add it with gut host add", nil)
+	}
+	repoPath := getRepoPath(remote.Url)
+	if repoPath == "" {
+		exitOnError("I can't find the path of the repository in "+remote.Url, nil)
+	}
+	return hostedRepo{wd: wd, remote: remote, hosting: hosting, path: repoPath}
+}
+
+// Credentials of the profile of the remote
+func (repo hostedRepo) credentials() provider.Credentials {
+	return getCredentials(getRemoteProfile(repo.wd, repo.remote.Name))
+}
+
+// Push the branch if the remote doesn't have all its commits
+func ensureBranchIsPushed(wd string, remote executor.Remote, branch string) {
+	head, err := executor.GetHeadHash(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the last commit 😢", err)
+	}
+	remoteBranch := executor.GetRemoteBranchName(wd, remote.Name, branch)
+	lease, err := executor.GetRemoteTrackingHash(wd, remote.Name, remoteBranch)
+	if err == nil && lease == head {
+		return
+	}
+	print.Message("%s/%s doesn't have all the commits of %s", print.Info, remote.Name, remoteBranch, branch)
+	res, err := prompt.InputBool("Do you want to push them first?", true)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		exitOnError("The pull request would miss some commits. Push them with gut push first", nil)
+	}
+	checkBranchProtection(wd, branch, actionPush)
+	pushBranch(wd, remote, branch, getRemoteProfile(wd, remote.Name))
+}
+
+// Open a pull request for the current branch with the API of the website hosting the repository
+func PullRequestCreate(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfDetachedHead(wd)
+
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	repo := getHostedRepo(wd)
+	remote, hosting := repo.remote, repo.hosting
+
+	base, _ := cmd.Flags().GetString("base")
+	if base == "" {
+		base, err = executor.GetDefaultBranch(wd)
+		if err != nil || base == "" {
+			exitOnError("I can't find the default branch. Choose the branch to merge into with --base", err)
+		}
+	}
+	if base == branch {
+		exitOnError("You are on "+base+". Switch to the branch with your changes first", nil)
+	}
+
+	ensureBranchIsPushed(wd, remote, branch)
+
+	/* ------------------------- Title and body from commits ------------------------ */
+	head, err := executor.GetHeadHash(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the last commit 😢", err)
+	}
+	// The remote branch is more likely to be up to date than the local one
+	baseHash, err := executor.GetRemoteTrackingHash(wd, remote.Name, base)
+	if err != nil {
+		baseCommit, err := executor.GetBranchCommit(wd, base)
+		if err != nil {
+			exitOnError("I can't find the branch "+base, err)
+		}
+		baseHash = baseCommit.Hash.String()
+	}
+	commits, err := executor.ListCommitsNotIn(wd, head, baseHash)
+	if err != nil {
+		exitOnError("Sorry, I can't list the commits of the branch 😢", err)
+	}
+	if len(commits) == 0 {
+		exitOnError(branch+" has no commit that "+base+" doesn't have", nil)
+	}
+	title, body := pullRequestFromCommits(branch, commits)
+	if cmd.Flags().Changed("body") {
+		body, _ = cmd.Flags().GetString("body")
+	}
+	if cmd.Flags().Changed("title") {
+		title, _ = cmd.Flags().GetString("title")
+	} else {
+		err = survey.AskOne(&survey.Input{
+			Message: "Title of the pull request:",
+			Default: title,
+		}, &title, survey.WithValidator(survey.Required))
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+	}
+
+	reviewers, _ := cmd.Flags().GetStringSlice("reviewer")
+	labels, _ := cmd.Flags().GetStringSlice("label")
+	draft, _ := cmd.Flags().GetBool("draft")
+	options := provider.PullRequestOptions{
+		Repo:      repo.path,
+		Head:      executor.GetRemoteBranchName(wd, remote.Name, branch),
+		Base:      base,
+		Title:     title,
+		Body:      body,
+		Reviewers: reviewers,
+		Labels:    labels,
+		Draft:     draft,
+	}
+
+	pr, err := hosting.CreatePullRequest(repo.credentials(), options)
+	var partial *provider.PartialError
+	if errors.As(err, &partial) {
+		print.Message("⚠️  %s", print.Warning, err.Error())
+	} else if err != nil {
+		exitOnError("Sorry, I can't open the "+hosting.PullRequestName()+" 😢", err)
+	}
+	print.Message("I've opened the %s #%d 🎉", print.Success, hosting.PullRequestName(), pr.Number)
+	print.Message("%s", print.None, pr.URL)
+}
+
+// Read the number of a pull request from an argument: 12, #12 or the URL of the pull request
+func parsePullRequestNumber(arg string) (int, error) {
+	arg = strings.TrimSuffix(strings.TrimSpace(arg), "/")
+	if i := strings.LastIndex(arg, "/"); i != -1 {
+		arg = arg[i+1:]
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("%s isn't the number of a pull request", arg)
+	}
+	return number, nil
+}
+
+// Name of the local branch a pull request is checked out to
+//
+// Branches of forks are prefixed so that they don't clash with the branches of the repository (e.g. main)
+func pullRequestBranchName(pr provider.PullRequest) string {
+	if pr.Fork || pr.Head == "" {
+		return fmt.Sprintf("pr/%d", pr.Number)
+	}
+	return pr.Head
+}
+
+// Describe a pull request on one line for gut pr list
+func formatPullRequest(pr provider.PullRequest) string {
+	description := color.GreenString("#%d", pr.Number) + " " + pr.Title
+	if pr.Draft {
+		description += color.YellowString(" [draft]")
+	}
+	head := pr.Head
+	if pr.Fork {
+		head = "fork:" + head
+	}
+	return description + color.HiBlackString(" by %s, %s → %s", pr.Author, head, pr.Base)
+}
+
+// List the open pull requests of the repository
+func PullRequestList(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	repo := getHostedRepo(wd)
+
+	prs, err := repo.hosting.ListPullRequests(repo.credentials(), repo.path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the "+repo.hosting.PullRequestName()+"s 😢", err)
+	}
+	if len(prs) == 0 {
+		print.Message("There is no open %s on %s", print.Info, repo.hosting.PullRequestName(), repo.path)
+		return
+	}
+	branch, _ := executor.GetCurrentBranch(wd)
+	for _, pr := range prs {
+		description := formatPullRequest(pr)
+		if !pr.Fork && pr.Head == executor.GetRemoteBranchName(wd, repo.remote.Name, branch) {
+			description += color.BlueString(" [current]")
+		}
+		fmt.Println(description)
+	}
+}
+
+// Fetch a pull request into a local branch and switch to it
+func PullRequestCheckout(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	number, err := parsePullRequestNumber(args[0])
+	if err != nil {
+		exitOnError("Usage: gut pr checkout <number>", err)
+	}
+	repo := getHostedRepo(wd)
+	credentials := repo.credentials()
+	pr, err := repo.hosting.GetPullRequest(credentials, repo.path, number)
+	if err != nil {
+		exitOnError(fmt.Sprintf("Sorry, I can't get the %s #%d 😢", repo.hosting.PullRequestName(), number), err)
+	}
+
+	ref := repo.hosting.PullRequestRef(number)
+	if ref == "" {
+		if pr.Fork {
+			exitOnError(fmt.Sprintf("#%d comes from a fork, and %s doesn't let me fetch it. Add the fork as a remote with gut remote add", number, repo.hosting.Name()), nil)
+		}
+		ref = "refs/heads/" + pr.Head
+	}
+	fetchedRef := fmt.Sprintf("refs/gut/pr/%d", number)
+	profileLocal := getRemoteProfile(wd, repo.remote.Name)
+	var fetched string
+	err = runWithProgress(fmt.Sprintf("Fetching #%d", number), func(ctx context.Context) error {
+		var err error
+		fetched, err = executor.FetchRef(ctx, wd, repo.remote.Name, ref, fetchedRef, profileLocal.Username, profileLocal.Password)
+		return err
+	})
+	if err != nil {
+		exitOnError(fmt.Sprintf("Sorry, I can't fetch #%d 😢", number), err)
+	}
+
+	branch := pullRequestBranchName(pr)
+	exists, err := executor.CheckIfBranchExists(wd, branch)
+	if err != nil {
+		exitOnError("I can't check if the branch exists", err)
+	}
+	if !exists {
+		err = executor.CreateBranchAt(wd, branch, fetched)
+		if err != nil {
+			exitOnError("My bad, I can't create the branch "+branch, err)
+		}
+		// gut sync and gut push then work on the branch of the pull request
+		if !pr.Fork {
+			err = executor.SetUpstream(wd, branch, repo.remote.Name, pr.Head)
+			if err != nil {
+				print.Message("I can't make %s track %s/%s: %s", print.Warning, branch, repo.remote.Name, pr.Head, err.Error())
+			}
+		}
+	}
+
+	Switch(cmd, []string{branch})
+
+	if exists {
+		head, err := executor.GetHeadHash(wd)
+		if err == nil && head != fetched {
+			// The local branch might have commits that aren't pushed yet
+			err = executor.GitMergeFastForward(fetchedRef)
+			if err != nil {
+				print.Message("%s has commits that #%d doesn't have, so I haven't updated it. The commits of #%d are at %s", print.Warning, branch, number, number, fetchedRef)
+			} else {
+				print.Message("I've updated %s with the last commits of #%d", print.Info, branch, number)
+			}
+		}
+	}
+	print.Message("You're reviewing #%d: %s (by %s)", print.Success, number, pr.Title, pr.Author)
+}
+
+// Show the pull request of the current branch, with its reviews and whether it can be merged
+func PullRequestStatus(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfDetachedHead(wd)
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	repo := getHostedRepo(wd)
+	credentials := repo.credentials()
+	name := repo.hosting.PullRequestName()
+
+	prs, err := repo.hosting.ListPullRequests(credentials, repo.path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the "+name+"s 😢", err)
+	}
+	remoteBranch := executor.GetRemoteBranchName(wd, repo.remote.Name, branch)
+	number := 0
+	for _, pr := range prs {
+		if !pr.Fork && pr.Head == remoteBranch {
+			number = pr.Number
+			break
+		}
+	}
+	if number == 0 {
+		print.Message("%s has no open %s. Open one with gut pr create", print.Info, branch, name)
+		return
+	}
+	pr, err := repo.hosting.GetPullRequest(credentials, repo.path, number)
+	if err != nil {
+		exitOnError(fmt.Sprintf("Sorry, I can't get the %s #%d 😢", name, number), err)
+	}
+
+	fmt.Println(formatPullRequest(pr))
+	fmt.Println(color.BlueString(pr.URL))
+	switch pr.Review {
+	case provider.ReviewApproved:
+		print.Message("Review: approved by %s", print.Success, strings.Join(pr.ApprovedBy, ", "))
+	case provider.ReviewChangesRequested:
+		print.Message("Review: changes requested", print.Error)
+	case provider.ReviewPending:
+		if len(pr.ApprovedBy) > 0 {
+			print.Message("Review: waiting for more approvals (approved by %s)", print.Warning, strings.Join(pr.ApprovedBy, ", "))
+		} else {
+			print.Message("Review: waiting for review", print.Warning)
+		}
+	}
+	switch pr.Mergeable {
+	case provider.MergeReady:
+		print.Message("Merge: ready to merge", print.Success)
+	case provider.MergeConflicts:
+		print.Message("Merge: has conflicts with %s. Merge %s into %s to resolve them", print.Error, pr.Base, pr.Base, branch)
+	case "":
+		print.Message("Merge: %s doesn't tell if it can be merged", print.Optional, repo.hosting.Name())
+	default:
+		print.Message("Merge: %s", print.Warning, pr.Mergeable)
+	}
+	if pr.Draft {
+		print.Message("It's a draft: mark it as ready for review on %s", print.Optional, repo.hosting.Name())
+	}
+}
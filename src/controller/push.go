@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+)
+
+// Push the current branch without pulling
+func Push(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfDetachedHead(wd)
+
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+
+	checkBranchProtection(wd, branch, actionPush)
+
+	// If the repository is mirrored, we push to every sync target
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	if len(conf.Sync.Push) > 0 {
+		remotes, err := executor.ListRemote(wd)
+		if err != nil {
+			exitOnError("Sorry, I can't get the remotes of the repository 😢", err)
+		}
+		pushToMirrors(wd, branch, conf.Sync.Pull, conf.Sync.Push, remotes, getSyncProfile(wd, false))
+		return
+	}
+
+	remote := getCurrentRemote(wd)
+	pushBranch(wd, remote, branch, getRemoteProfile(wd, remote.Name))
+}
+
+// Push a branch to its upstream. Refuse if the remote branch has commits the branch doesn't have
+func pushBranch(path string, remote executor.Remote, branch string, profileLocal profile.Profile) {
+	remoteBranch := executor.GetRemoteBranchName(path, remote.Name, branch)
+
+	// Same check as gut sync in case the history has been rewritten
+	lease, err := executor.GetRemoteTrackingHash(path, remote.Name, remoteBranch)
+	if err == nil && hasRewrittenHistory(path, branch, lease) {
+		if !syncRewrittenHistory(path, remote, branch, lease, profileLocal) {
+			print.Message("Run gut pull to integrate them, then gut push", print.Optional)
+			os.Exit(1)
+		}
+		return
+	}
+
+	err = runWithProgress("Pushing "+branch+" to "+remote.Name, func(ctx context.Context) error {
+		return executor.Push(ctx, path, remote.Name, branch, profileLocal.Username, profileLocal.Password)
+	})
+	if isAuthError(err) {
+		print.Message("Uh oh, your credentials are wrong 😢. Please select another profile.", print.Error)
+		pushBranch(path, remote, branch, getSyncProfile(path, true))
+		return
+	} else if err == git.ErrNonFastForwardUpdate {
+		print.Message("%s/%s has commits you don't have. Run gut pull to integrate them first", print.Error, remote.Name, remoteBranch)
+		os.Exit(1)
+	} else if err == git.NoErrAlreadyUpToDate {
+		print.Message("%s/%s is already up to date", print.Success, remote.Name, remoteBranch)
+		return
+	} else if err != nil {
+		exitOnError("Sorry, I can't push the repository 😢", err)
+	}
+	print.Message("I've pushed %s to %s/%s 🎉", print.Success, branch, remote.Name, remoteBranch)
+}
@@ -110,6 +110,27 @@ func getRemote(path string) (executor.Remote, error) {
 
 }
 
+// Get the remote tracked by the branch
+//
+// If the branch doesn't track any remote branch, we fall back on getRemote
+func getRemoteForBranch(path string, branch string) (executor.Remote, error) {
+	upstream, err := executor.GetUpstream(path, branch)
+	if err != nil || upstream.Remote == "" {
+		return getRemote(path)
+	}
+	remotes, err := executor.ListRemote(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the remote of the repository", err)
+	}
+	for _, remote := range remotes {
+		if remote.Name == upstream.Remote {
+			return remote, nil
+		}
+	}
+	// The remote tracked has been removed
+	return getRemote(path)
+}
+
 func Remote(cmd *cobra.Command, args []string) {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// Maximum number of lost commits offered by gut rescue
+const maxLostCommits = 30
+
+// A commit that no branch leads to anymore
+type lostCommit struct {
+	Hash  string
+	Title string
+	// When the commit was made
+	Date time.Time
+	lostReason
+}
+
+// What made a commit lost. Operation is empty if it's unknown
+type lostReason struct {
+	// Message of the reflog (e.g. reset: moving to HEAD~1) or command of gut (e.g. gut squash)
+	Operation string
+	When      time.Time
+}
+
+// Find which operation made each lost commit unreachable, from the reflogs and the journal of gut
+//
+// An operation made a commit lost if it moved a ref away from it. The latest one is kept
+func explainLostCommits(lost map[string]bool, reflogs map[string][]executor.ReflogEntry, journal []executor.JournalEntry) map[string]lostReason {
+	reasons := map[string]lostReason{}
+	found := func(hash string, reason lostReason) {
+		if !lost[hash] {
+			return
+		}
+		if previous, ok := reasons[hash]; !ok || reason.When.After(previous.When) {
+			reasons[hash] = reason
+		}
+	}
+	for _, entries := range reflogs {
+		for _, entry := range entries {
+			if entry.Old != entry.New {
+				found(entry.Old, lostReason{Operation: entry.Message, When: entry.When})
+			}
+		}
+	}
+	// gut uses go-git for most operations, which doesn't write the reflogs
+	for _, entry := range journal {
+		for ref, hash := range entry.Before.Refs {
+			if entry.After.Refs[ref] != hash {
+				found(hash, lostReason{Operation: entry.Command, When: entry.When})
+			}
+		}
+		if entry.Before.HeadHash != entry.After.HeadHash {
+			found(entry.Before.HeadHash, lostReason{Operation: entry.Command, When: entry.When})
+		}
+	}
+	return reasons
+}
+
+// Return true if the commit is one of the commits git makes to store a stash
+//
+// They are handled by gut stash, so they aren't offered by gut rescue
+func isStashCommit(title string) bool {
+	for _, prefix := range []string{"WIP on ", "index on ", "untracked files on "} {
+		if strings.HasPrefix(title, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// List the commits lost recently, the most recently lost first
+func findLostCommits(path string) []lostCommit {
+	hashes, err := executor.ListDanglingCommits()
+	if err != nil {
+		exitOnError("Sorry, I can't look for the lost commits 😢", err)
+	}
+	reflogs, err := executor.ReadAllReflogs(path)
+	if err != nil {
+		exitOnError("Sorry, I can't read the reflogs 😢", err)
+	}
+	journal, err := executor.ReadJournal(path)
+	if err != nil {
+		exitOnError("Sorry, I can't read the journal of gut 😢", err)
+	}
+
+	lost := map[string]bool{}
+	for _, hash := range hashes {
+		lost[hash] = true
+	}
+	reasons := explainLostCommits(lost, reflogs, journal)
+
+	var commits []lostCommit
+	for _, hash := range hashes {
+		commit, err := executor.GetCommit(path, hash)
+		if err != nil {
+			continue
+		}
+		title := getTitleFromCommit(commit.Message)
+		if isStashCommit(title) {
+			continue
+		}
+		commits = append(commits, lostCommit{Hash: hash, Title: title, Date: commit.Committer.When, lostReason: reasons[hash]})
+	}
+	// Commits lost for an unknown reason are sorted by their date
+	lostAt := func(commit lostCommit) time.Time {
+		if commit.When.IsZero() {
+			return commit.Date
+		}
+		return commit.When
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		return lostAt(commits[i]).After(lostAt(commits[j]))
+	})
+	if len(commits) > maxLostCommits {
+		commits = commits[:maxLostCommits]
+	}
+	return commits
+}
+
+// Find the commits lost after an undo, a squash, a goto... and bring them back
+func Rescue(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+
+	sp := spinner.New(spinner.CharSets[9], 100*time.Millisecond)
+	sp.Suffix = " I'm looking for your lost commits..."
+	sp.Start()
+	commits := findLostCommits(wd)
+	sp.Stop()
+	if len(commits) == 0 {
+		print.Message("I haven't found any lost commit. Your work is safe 🎉", print.Success)
+		return
+	}
+
+	now := time.Now()
+	options := make([]string, len(commits))
+	for i, commit := range commits {
+		option := fmt.Sprintf("%s %s %s", color.HiYellowString(commit.Hash[:7]), color.HiCyanString(commit.Title), color.HiBlackString("(committed "+formatAge(commit.Date, now)+")"))
+		if commit.Operation != "" {
+			option += color.HiBlackString(fmt.Sprintf(" lost %s by %s", formatAge(commit.When, now), commit.Operation))
+		}
+		options[i] = option
+	}
+	print.Message("I've found %d lost commit(s). Git deletes them after a few weeks, so don't wait too long", print.Info, len(commits))
+	var answer int
+	err := survey.AskOne(&survey.Select{
+		Message:  "Which commit do you want to bring back?",
+		Options:  options,
+		PageSize: 15,
+	}, &answer)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	commit := commits[answer]
+
+	const (
+		newBranch = "Restore it as a new branch"
+		reset     = "Reset the current branch to it"
+		cancel    = "Cancel"
+	)
+	res, err := prompt.InputSelect("What do you want to do with "+commit.Hash[:7]+"?", []string{newBranch, reset, cancel})
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	switch res {
+	case newBranch:
+		rescueAsBranch(wd, commit)
+	case reset:
+		rescueWithReset(wd, commit)
+	}
+}
+
+// Create a new branch pointing to the lost commit
+func rescueAsBranch(wd string, commit lostCommit) {
+	var name string
+	err := survey.AskOne(&survey.Input{
+		Message: "Name of the new branch:",
+		Default: "rescue/" + commit.Hash[:7],
+	}, &name, survey.WithValidator(survey.Required))
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	name = enforceBranchConvention(wd, strings.TrimSpace(name))
+	if err := executor.ValidateBranchName(name); err != nil {
+		exitOnError(name+" isn't a valid branch name", err)
+	}
+	exists, err := executor.CheckIfBranchExists(wd, name)
+	if err != nil {
+		exitOnError("I can't check if the branch exists", err)
+	}
+	if exists {
+		print.Message("The branch %s already exists, choose another name", print.Error, name)
+		os.Exit(1)
+	}
+	err = executor.CreateBranchAt(wd, name, commit.Hash)
+	if err != nil {
+		exitOnError("My bad, I can't create the branch "+name, err)
+	}
+	print.Message("I've restored %s on the branch %s 🎉", print.Success, commit.Hash[:7], name)
+	print.Message("Switch to it with gut switch %s", print.Optional, name)
+}
+
+// Move the current branch to the lost commit
+func rescueWithReset(wd string, commit lostCommit) {
+	checkIfDetachedHead(wd)
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	clean, err := executor.IsWorkTreeClean(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommitted changes", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+	protectionTrailer := checkCurrentBranchProtection(wd, actionRewrite)
+
+	res, err := prompt.InputBool(fmt.Sprintf("%s will point to %s. The commits of %s that aren't in %s won't be on it anymore (gut rescue can find them). Continue?", branch, commit.Hash[:7], branch, commit.Hash[:7]), false)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't change anything", print.Info)
+		return
+	}
+	err = executor.GitResetHard(commit.Hash)
+	if err != nil {
+		exitOnError("Sorry, I can't reset "+branch+" to "+commit.Hash[:7]+" 😢", err)
+	}
+	recordProtectionOverride(wd, protectionTrailer)
+	print.Message("%s is back at %s 🎉", print.Success, branch, commit.Hash[:7])
+}
@@ -62,6 +62,9 @@ func Revert(cmd *cobra.Command, args []string) {
 	// Check if Git CLI is installed
 	checkIfGitInstalled()
 
+	// Reverting creates a commit, which protected branches might forbid
+	protectionTrailer := checkCurrentBranchProtection(wd, actionSave)
+
 	print.Message("Undo reverts your working tree to a commit of your choice", print.Info)
 
 	// Check if the working tree is clean (no uncommitted changes)
@@ -94,15 +97,22 @@ func Revert(cmd *cobra.Command, args []string) {
 	commit := chooseCommit(commits)
 	fmt.Fprintf(color.Output, "I will revert the commit to %s created by %s on %s \n\n", color.HiCyanString(getTitleFromCommit(commit.Message)), color.HiCyanString(commit.Author.Name), commit.Author.When.Format("Mon Jan 2 15:04:05 2006"))
 
+	// The uncommitted changes are lost when reverting
+	snapshot := ""
+	if !wtClean {
+		snapshot = snapshotBeforeDiscard(wd, "gut revert")
+	}
 	err = executor.GitRevert(commit.Hash.String())
 	if err != nil {
 		exitOnError("Sorry, I can't revert the commit. An error occured while calling 'git revert --no-edit "+commit.Hash.String()+"' 😢", err)
 	}
+	recordProtectionOverride(wd, protectionTrailer)
 	err = executor.AddAll(wd)
 	if err != nil {
 		exitOnError("Sorry, I can't add all the files 😢", err)
 	}
 
 	print.Message("I've successfully reverted to "+getTitleFromCommit(commit.Message), print.Success)
+	printSnapshotRestore(snapshot)
 
 }
@@ -0,0 +1,459 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// What gut rewrite does with a commit
+const (
+	rewritePick   = "keep"
+	rewriteReword = "reword"
+	rewriteDrop   = "drop"
+	rewriteSquash = "squash"
+	// Like squash, but the message of the commit is discarded
+	rewriteFixup = "fixup"
+	rewriteEdit  = "edit"
+)
+
+// A commit of gut rewrite and what to do with it
+type rewriteStep struct {
+	Hash   string
+	Title  string
+	Action string
+	// New message of a reworded commit
+	Message string
+}
+
+// Check that the steps (oldest first) can be applied
+func validateRewrite(steps []rewriteStep) error {
+	for _, step := range steps {
+		if step.Action == rewriteDrop {
+			continue
+		}
+		if step.Action == rewriteSquash || step.Action == rewriteFixup {
+			return fmt.Errorf("%s can't be squashed: there is no previous commit to squash it into", step.Hash[:7])
+		}
+		return nil
+	}
+	return nil
+}
+
+// Write the todo list of git rebase -i for the steps (oldest first)
+//
+// Reworded commits are amended with the message in messageFile(i) once the commits squashed into them are applied
+func buildRebaseTodo(steps []rewriteStep, messageFile func(i int) string) string {
+	var lines []string
+	// Index of the commit the next squashed commits go into
+	head := -1
+	amendHead := func() {
+		if head != -1 && steps[head].Action == rewriteReword {
+			lines = append(lines, "exec git commit --amend --only --allow-empty --quiet -F "+executor.ShellQuote(messageFile(head)))
+		}
+	}
+	for i, step := range steps {
+		switch step.Action {
+		case rewriteDrop:
+			lines = append(lines, "drop "+step.Hash+" "+step.Title)
+		case rewriteSquash, rewriteFixup:
+			lines = append(lines, step.Action+" "+step.Hash+" "+step.Title)
+		default:
+			amendHead()
+			head = i
+			command := "pick"
+			if step.Action == rewriteEdit {
+				command = "edit"
+			}
+			lines = append(lines, command+" "+step.Hash+" "+step.Title)
+		}
+	}
+	amendHead()
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Describe the history after the rewrite of the steps (oldest first), newest commit first
+func previewRewrite(steps []rewriteStep) []string {
+	type result struct {
+		step     rewriteStep
+		squashed int
+	}
+	var results []result
+	for _, step := range steps {
+		switch step.Action {
+		case rewriteDrop:
+		case rewriteSquash, rewriteFixup:
+			if len(results) > 0 {
+				results[len(results)-1].squashed++
+			}
+		default:
+			results = append(results, result{step: step})
+		}
+	}
+	lines := make([]string, 0, len(results))
+	for i := len(results) - 1; i >= 0; i-- {
+		step := results[i].step
+		title := step.Title
+		var notes []string
+		switch step.Action {
+		case rewriteReword:
+			title = getTitleFromCommit(step.Message)
+			notes = append(notes, "reworded")
+		case rewriteEdit:
+			notes = append(notes, "paused to edit")
+		}
+		if results[i].squashed > 0 {
+			notes = append(notes, fmt.Sprintf("+%d squashed", results[i].squashed))
+		}
+		line := step.Hash[:7] + " " + title
+		if len(notes) > 0 {
+			line += " (" + strings.Join(notes, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Let the user choose what to do with each commit (oldest first)
+//
+// Return false if the user cancels
+func editRewriteSteps(steps []rewriteStep) bool {
+	const (
+		apply  = "✅ Rewrite the history"
+		cancel = "❌ Cancel"
+	)
+	const (
+		keep     = "Keep it as is"
+		reword   = "Reword its message"
+		drop     = "Drop it"
+		squash   = "Squash it into the previous commit"
+		edit     = "Pause on it to change its content"
+		moveUp   = "Move it up (newer)"
+		moveDown = "Move it down (older)"
+	)
+	for {
+		print.Message("\nHistory after the rewrite (newest first):", print.None)
+		preview := previewRewrite(steps)
+		if len(preview) == 0 {
+			print.Message("\t(no commit)", print.Optional)
+		}
+		for _, line := range preview {
+			fmt.Fprintf(color.Output, "\t%s\n", line)
+		}
+
+		// The commits are shown newest first, like gut history
+		options := make([]string, 0, len(steps)+2)
+		for i := len(steps) - 1; i >= 0; i-- {
+			step := steps[i]
+			options = append(options, fmt.Sprintf("%-7s %s %s", step.Action, color.HiYellowString(step.Hash[:7]), step.Title))
+		}
+		options = append(options, apply, cancel)
+		var answer int
+		err := survey.AskOne(&survey.Select{
+			Message:  "Which commit do you want to change?",
+			Options:  options,
+			PageSize: 15,
+		}, &answer)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		switch options[answer] {
+		case cancel:
+			return false
+		case apply:
+			if err := validateRewrite(steps); err != nil {
+				print.Message(err.Error(), print.Error)
+				continue
+			}
+			return true
+		}
+
+		i := len(steps) - 1 - answer
+		res, err := prompt.InputSelect("What do you want to do with "+steps[i].Hash[:7]+"?", []string{keep, reword, drop, squash, edit, moveUp, moveDown})
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		switch res {
+		case keep:
+			steps[i].Action = rewritePick
+		case reword:
+			print.Message("Let's write the new message of %s", print.None, steps[i].Hash[:7])
+			steps[i].Message = promptCommitMessage("", "")
+			steps[i].Action = rewriteReword
+		case drop:
+			steps[i].Action = rewriteDrop
+		case squash:
+			steps[i].Action = rewriteSquash
+		case edit:
+			steps[i].Action = rewriteEdit
+		case moveUp:
+			if i < len(steps)-1 {
+				steps[i], steps[i+1] = steps[i+1], steps[i]
+			}
+		case moveDown:
+			if i > 0 {
+				steps[i], steps[i-1] = steps[i-1], steps[i]
+			}
+		}
+	}
+}
+
+// Folder where gut rewrite keeps the new messages and the protection trailer until the rewrite is done
+func rewriteStateDir(path string) string {
+	return filepath.Join(path, ".git", "gut", "rewrite")
+}
+
+// Return true if the rebase in progress has been started by gut rewrite or gut squash --auto
+func isRewriteInProgress(path string) bool {
+	_, err := os.Stat(rewriteStateDir(path))
+	return err == nil
+}
+
+// Tell the user a rebase gut didn't start is in progress, and how to finish it
+func describeForeignRebase(path string) {
+	if state, err := executor.GetRebaseState(path); err == nil && state.Branch != "" {
+		print.Message("A git rebase of %s is in progress, but gut rewrite didn't start it (e.g. git pull --rebase from gut sync)", print.Warning, state.Branch)
+	} else {
+		print.Message("A git rebase is in progress, but gut rewrite didn't start it (e.g. git pull --rebase from gut sync)", print.Warning)
+	}
+	print.Message("Finish it with git rebase --continue, or cancel it with git rebase --abort. Then run gut rewrite again", print.Optional)
+}
+
+// Finish or pause the rewrite in progress, depending on where git rebase stopped
+//
+// Conflicts are resolved one commit at a time. Return true once the rewrite is done
+func followRewrite(wd string) bool {
+	const (
+		continueRewrite = "I've fixed the conflicts, continue"
+		keepBefore      = "Keep the version before this commit for a file"
+		takeCommit      = "Take the version of this commit for a file"
+		skipCommit      = "Skip this commit"
+		abortRewrite    = "Abort the rewrite and restore the branch"
+		later           = "Stop here, I'll fix them later"
+	)
+	for executor.IsRebasing(wd) {
+		state, err := executor.GetRebaseState(wd)
+		if err != nil {
+			exitOnError("Sorry, I can't read the state of the rewrite 😢", err)
+		}
+		conflicts, err := executor.GitListConflicts()
+		if err != nil {
+			exitOnError("Sorry, I can't list the files with conflicts 😢", err)
+		}
+		if len(conflicts) == 0 && state.Edit {
+			print.Message("I've paused on %s (step %d of %d)", print.Info, shortHash(state.Stopped), state.Done, state.Total)
+			print.Message("Change what you want, then run gut rewrite --continue to add your changes to the commit. Run gut rewrite --abort to restore the branch", print.None)
+			return false
+		}
+
+		if len(conflicts) > 0 {
+			print.Message("\n%s doesn't apply cleanly (step %d of %d). These files have conflicts:", print.Warning, shortHash(state.Stopped), state.Done, state.Total)
+			for _, file := range conflicts {
+				fmt.Fprintf(color.Output, "\t%s\n", color.RedString(file))
+			}
+			print.Message("Open them in your editor and keep the right changes between <<<<<<< and >>>>>>>", print.Optional)
+		} else {
+			print.Message("\nThe rewrite stopped at step %d of %d", print.Warning, state.Done, state.Total)
+		}
+		options := []string{continueRewrite, skipCommit, abortRewrite, later}
+		if len(conflicts) > 0 {
+			options = []string{continueRewrite, keepBefore, takeCommit, skipCommit, abortRewrite, later}
+		}
+		res, err := prompt.InputSelect("What do you want to do?", options)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+
+		switch res {
+		case continueRewrite:
+			unresolved := false
+			for _, file := range conflicts {
+				markers, err := executor.HasConflictMarkers(wd, file)
+				if err != nil {
+					exitOnError("Sorry, I can't read "+file+" 😢", err)
+				}
+				if markers {
+					print.Message("%s still has conflict markers", print.Warning, file)
+					unresolved = true
+					continue
+				}
+				err = executor.GitAddFile(file)
+				if err != nil {
+					exitOnError("Sorry, I can't mark "+file+" as resolved 😢", err)
+				}
+			}
+			if !unresolved {
+				// The next commits might stop again, which is handled by the loop
+				executor.GitRebaseContinue()
+			}
+		case keepBefore, takeCommit:
+			file, err := prompt.InputSelect("Which file?", conflicts)
+			if err != nil {
+				exitOnKnownError(errorReadInput, err)
+			}
+			// While rewriting, "ours" is the history rewritten so far and "theirs" the commit applied
+			err = executor.GitResolveWith(file, res == keepBefore)
+			if err != nil {
+				print.Message("I can't resolve %s this way, fix it in your editor 😓", print.Error, file)
+			}
+		case skipCommit:
+			executor.GitRebaseSkip()
+		case abortRewrite:
+			abortRewriteInProgress(wd)
+			return false
+		case later:
+			print.Message("Okay. Run gut rewrite --continue when you're ready, or gut rewrite --abort to restore the branch", print.Info)
+			return false
+		}
+	}
+
+	// The rewrite is done
+	trailer, _ := os.ReadFile(filepath.Join(rewriteStateDir(wd), "trailer"))
+	os.RemoveAll(rewriteStateDir(wd))
+	recordProtectionOverride(wd, string(trailer))
+	print.Message("I've rewritten the history 🎉", print.Success)
+	return true
+}
+
+// Return the first 7 characters of a hash
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// Abort the rewrite in progress and restore the tip of the branch
+func abortRewriteInProgress(wd string) {
+	state, _ := executor.GetRebaseState(wd)
+	err := executor.GitRebaseAbort()
+	if err != nil {
+		if state.OrigHead == "" {
+			exitOnError("Sorry, I can't abort the rewrite 😢", err)
+		}
+		exitOnError("Sorry, I can't abort the rewrite 😢. The branch was at "+state.OrigHead+": restore it with git rebase --abort or git reset --hard "+state.OrigHead, err)
+	}
+	os.RemoveAll(rewriteStateDir(wd))
+	print.Message("I've aborted the rewrite. The branch is back as before", print.Success)
+}
+
+// Reorder, reword, drop, squash or edit the commits that aren't pushed yet
+func Rewrite(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+
+	continueFlag, _ := cmd.Flags().GetBool("continue")
+	abortFlag, _ := cmd.Flags().GetBool("abort")
+	if executor.IsRebasing(wd) {
+		// gut only takes over the rebases it started, not e.g. the git pull --rebase of gut sync
+		if !isRewriteInProgress(wd) {
+			describeForeignRebase(wd)
+			os.Exit(1)
+		}
+		switch {
+		case abortFlag:
+			abortRewriteInProgress(wd)
+		case continueFlag:
+			// Changes made while paused on a commit are added to it
+			err := executor.AddAll(wd)
+			if err != nil {
+				exitOnError("Sorry, I can't add your changes 😢", err)
+			}
+			executor.GitRebaseContinue()
+			followRewrite(wd)
+		default:
+			print.Message("A rewrite is in progress", print.Info)
+			followRewrite(wd)
+		}
+		return
+	}
+	// Left by a rewrite that has been finished outside of gut, it would make gut take over the next rebase
+	os.RemoveAll(rewriteStateDir(wd))
+	if continueFlag || abortFlag {
+		print.Message("There is no rewrite in progress", print.Info)
+		return
+	}
+
+	checkIfDetachedHead(wd)
+	verifUserConfig(wd)
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	clean, err := executor.IsWorkTreeClean(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommitted changes", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+	protectionTrailer := checkCurrentBranchProtection(wd, actionRewrite)
+
+	commits := listUnpushedCommits(wd)
+	if len(commits) == 0 {
+		print.Message("All the commits of %s are pushed, so I can't rewrite them", print.Info, branch)
+		return
+	}
+	base := commits[len(commits)-1].ParentHashes[0].String()
+	steps := make([]rewriteStep, len(commits))
+	for i, commit := range commits {
+		// Steps are oldest first, like git rebase
+		steps[len(commits)-1-i] = rewriteStep{
+			Hash:   commit.Hash.String(),
+			Title:  getTitleFromCommit(commit.Message),
+			Action: rewritePick,
+		}
+	}
+	print.Message("You can rewrite the %d commit(s) of %s that aren't pushed yet", print.Info, len(commits), branch)
+	if !editRewriteSteps(steps) {
+		print.Message("Okay, I won't rewrite anything", print.Info)
+		return
+	}
+
+	startRewrite(wd, base, steps, protectionTrailer)
+}
+
+// Rewrite the commits after base with the steps (oldest first), and follow the rewrite until it's done or paused
+//
+// The trailer is added to the last commit once the rewrite is done
+func startRewrite(wd string, base string, steps []rewriteStep, protectionTrailer string) {
+	/* ---------------------------- Save what the rebase needs --------------------------- */
+	dir := rewriteStateDir(wd)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		exitOnError("Sorry, I can't prepare the rewrite 😢", err)
+	}
+	messageFile := func(i int) string {
+		return filepath.ToSlash(filepath.Join(dir, fmt.Sprintf("message-%d", i)))
+	}
+	for i, step := range steps {
+		if step.Action == rewriteReword {
+			err = os.WriteFile(messageFile(i), []byte(step.Message), 0644)
+			if err != nil {
+				exitOnError("Sorry, I can't prepare the rewrite 😢", err)
+			}
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, "trailer"), []byte(protectionTrailer), 0644)
+	if err != nil {
+		exitOnError("Sorry, I can't prepare the rewrite 😢", err)
+	}
+
+	err = executor.GitRebaseInteractive(wd, base, buildRebaseTodo(steps, messageFile))
+	// An error while the rebase is still in progress means it stopped on a commit, which followRewrite handles
+	if err != nil && !executor.IsRebasing(wd) {
+		os.RemoveAll(dir)
+		exitOnError("Sorry, I can't rewrite the history 😢", err)
+	}
+	followRewrite(wd)
+}
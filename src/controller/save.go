@@ -9,6 +9,7 @@
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/briandowns/spinner"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
 	"github.com/julien040/gut/src/prompt"
@@ -109,6 +110,16 @@ func Save(cmd *cobra.Command, args []string) {
 	// Check if the user config is set
 	verifUserConfig(wd)
 
+	// Protected branches might forbid saving on them
+	protectionTrailer := checkSaveProtection(wd)
+
+	// With --fixup and no value, the commit might be the first argument (gut save --fixup 3b4c5d6)
+	fixup := cmd.Flag("fixup").Value.String()
+	if fixup == fixupChoose && len(args) > 0 && !checkIfPathExist(mergeLocalPathWithCurrDir(args[0])) {
+		fixup = args[0]
+		args = args[1:]
+	}
+
 	// Check if files have been passed as arguments
 	if len(args) > 0 {
 		err = validatePaths(args)
@@ -140,7 +151,10 @@ func Save(cmd *cobra.Command, args []string) {
 	var commitMessage string
 	sp := spinner.New(spinner.CharSets[9], 100*time.Millisecond)
 
-	if editor == "none" {
+	if fixup != "" {
+		target := chooseFixupTarget(wd, fixup)
+		commitMessage = fixupTitle(listUnpushedCommits(wd), target)
+	} else if editor == "none" {
 		commitMessage = promptCommitMessage(title, message)
 	} else {
 		sp.Suffix = " I'm waiting for you to write your commit message... 🥱"
@@ -149,6 +163,8 @@ func Save(cmd *cobra.Command, args []string) {
 		sp.Stop()
 	}
 
+	commitMessage = addCommitTrailer(commitMessage, protectionTrailer)
+
 	// Launch the spinner
 	sp.Suffix = " I'm committing your changes..."
 	sp.Start()
@@ -164,6 +180,33 @@ func Save(cmd *cobra.Command, args []string) {
 
 }
 
+// Value of the --fixup flag when no commit is given
+const fixupChoose = "choose"
+
+// Return the commit a fixup commit fixes, among the commits gut squash --auto can rewrite (see listUnpushedCommits)
+//
+// If hash is fixupChoose or isn't one of them, the user picks one
+func chooseFixupTarget(wd string, hash string) object.Commit {
+	// A fixup commit is squashed later, which rewrites the commit it fixes. So only unpushed commits are offered
+	commits := listUnpushedCommits(wd)
+	if len(commits) == 0 {
+		print.Message("All your commits are pushed, so there is no commit to fix. Use gut save to make a new commit", print.Warning)
+		os.Exit(0)
+	}
+	if hash != fixupChoose {
+		target, err := executor.GetCommitByHash(wd, hash)
+		if err == nil {
+			for _, commit := range commits {
+				if commit.Hash == target.Hash {
+					return commit
+				}
+			}
+		}
+		print.Message("%s isn't a commit of this branch I can fix (it might be pushed already). Please choose a commit from the list below", print.Warning, hash)
+	}
+	return chooseCommit(commits)
+}
+
 func promptCommitMessage(title string, message string) string {
 	var answers struct {
 		Type        int
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+)
+
+// How long the snapshots are kept when the .gut file doesn't say
+const defaultSnapshotRetentionDays = 14
+
+// Return how long the snapshots are kept, from the [snapshot] section of the .gut file
+//
+// Return 0 if they're never pruned
+func getSnapshotRetention(wd string) time.Duration {
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	days := conf.Snapshot.RetentionDays
+	switch {
+	case days == 0:
+		days = defaultSnapshotRetentionDays
+	case days < 0:
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Save the uncommitted changes, untracked files included, before gut discards them
+//
+// The expired snapshots are pruned first. If the changes can't be saved, gut exits without discarding them.
+// Return the ref of the snapshot, or an empty string if there was nothing to save
+func snapshotBeforeDiscard(wd string, operation string) string {
+	if retention := getSnapshotRetention(wd); retention > 0 {
+		// A snapshot that can't be pruned is only kept longer
+		executor.PruneSnapshots(time.Now().Add(-retention))
+	}
+	ref, err := executor.CreateSnapshot(wd, "gut snapshot before "+operation)
+	if err != nil {
+		exitOnError("I can't save a snapshot of your changes, so I haven't discarded them", err)
+	}
+	return ref
+}
+
+// Tell the user how to get the changes of a snapshot back
+//
+// If files are given, only these files have been discarded
+func printSnapshotRestore(ref string, files ...string) {
+	if ref == "" {
+		return
+	}
+	print.Message("Your changes are saved. Get them back with: %s", print.Optional, executor.SnapshotRestoreCommand(ref, files...))
+}
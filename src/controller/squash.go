@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/spf13/cobra"
 
@@ -56,6 +59,13 @@ func Squash(cmd *cobra.Command, args []string) {
 	// Check if the user has a valid configuration
 	verifUserConfig(wd)
 
+	protectionTrailer := checkCurrentBranchProtection(wd, actionRewrite)
+
+	if auto, _ := cmd.Flags().GetBool("auto"); auto {
+		autosquash(wd, protectionTrailer)
+		return
+	}
+
 	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond)
 	s.Prefix = "Listing all commits... "
 	s.Start()
@@ -66,31 +76,21 @@ func Squash(cmd *cobra.Command, args []string) {
 		exitOnError("Sorry, I can't list the commits", err)
 	}
 
+	// Check if there is enough commits to squash
+	if len(commits) < 2 {
+		print.Message("You don't have enough commits to squash. Please make at least 2 commits before squashing them", print.Warning)
+		os.Exit(0)
+	}
+
 	// Get the index of the latest commit that has been pushed
-	// Because gut doesn't allow rewriting the history, we can't squash a commit that has been pushed
+	// Squashing a commit that has been pushed rewrites the history, so we warn the user
 	s.Prefix = "Checking if your commits have been pushed... "
 	s.Start()
 	indexLatestCommitPushed := getIndexLatestCommitPushed(commits)
 	s.Stop()
 
-	// It means the user has already pushed all his commits
-	// We can't squash them because it would rewrite the history
-	// So we exit the program
 	if indexLatestCommitPushed == 0 {
-		print.Message("All your commits have been pushed. Because I won't allow any rewrite of the history, I can't squash your commits", print.Warning)
-		os.Exit(0)
-	}
-
-	// Check if there is enough commits to squash
-	if len(commits) < 2 || indexLatestCommitPushed == 1 {
-		print.Message("You don't have enough commits to squash. Please make at least 2 commits before squashing them", print.Warning)
-		os.Exit(0)
-	}
-
-	// If no commit has been pushed, we set the index to the length of the commits
-	// So the user can squash all his commits
-	if indexLatestCommitPushed == -1 {
-		indexLatestCommitPushed = len(commits)
+		print.Message("All your commits have been pushed. Squashing them will rewrite the history of the remote branch", print.Warning)
 	}
 
 	// The object.Commit to squash
@@ -101,7 +101,7 @@ func Squash(cmd *cobra.Command, args []string) {
 	// Prompt the user to choose a commit to squash
 	promptCommitToSquash := func() object.Commit {
 		// We start at 1 because we don't want to squash the latest commit
-		return chooseCommit(commits[1:indexLatestCommitPushed])
+		return chooseCommit(commits[1:])
 	}
 
 	// If the user has passed a commit hash as argument
@@ -112,23 +112,33 @@ func Squash(cmd *cobra.Command, args []string) {
 		// We retrieve the commit object from the short hash
 		commitToSquash, err = executor.GetCommitByHash(wd, commitArg)
 
-		// Case 1: The commit doesn't exist
+		// The commit doesn't exist
 		if err != nil {
 			print.Message("I can't find the commit %s. Please choose a commit from the list below", print.Warning, commitArg)
 			commitToSquash = promptCommitToSquash()
-
-		} else if executor.GitRemoteContainsHash(commitToSquash.Hash.String()) { // Case 2: The commit has already been pushed
-			print.Message("The commit %s has already been pushed. I don't allow rewriting of the history. Please choose a commit from the list below", print.Warning, commitArg)
-			commitToSquash = promptCommitToSquash()
 		}
 
 	} else { // If the user hasn't passed a commit hash as argument
 		commitToSquash = promptCommitToSquash()
 	}
 
+	// Because we soft reset to the commit to squash, it's rewritten as well as all the commits after it
+	// If it's older than the latest commit pushed, we are rewriting pushed commits
+	if indexLatestCommitPushed != -1 {
+		for i, commit := range commits {
+			if commit.Hash == commitToSquash.Hash {
+				if i >= indexLatestCommitPushed && !confirmRewritePushedCommits() {
+					print.Message("Okay, I won't squash your commits", print.Info)
+					os.Exit(0)
+				}
+				break
+			}
+		}
+	}
+
 	print.Message("Choose a new message for the commit", print.Info)
 	// Choose a new message for the commit
-	newMessage := promptCommitMessage("", "")
+	newMessage := addCommitTrailer(promptCommitMessage("", ""), protectionTrailer)
 
 	res, err := prompt.InputBool("Are you sure you want to squash all commits to "+commitToSquash.Hash.String()+"?", false)
 	if err != nil {
@@ -159,3 +169,117 @@ func Squash(cmd *cobra.Command, args []string) {
 	print.Message("The commit has been successfully squashed", print.Success)
 
 }
+
+// Prefix of the title of the commits made with gut save --fixup
+const fixupPrefix = "fixup! "
+
+// Return the title of a commit fixing target. commits are the commits not pushed yet, newest first
+//
+// The title of target tells gut squash --auto which commit to squash the fixup into. If a newer commit
+// has the same title, the fixup would go into it, so the short hash of target is used instead
+func fixupTitle(commits []object.Commit, target object.Commit) string {
+	title := getTitleFromCommit(target.Message)
+	// The fixup of a fixup is matched by the title of the original commit
+	if strings.HasPrefix(title, fixupPrefix) {
+		return fixupPrefix + title
+	}
+	for _, commit := range commits {
+		if commit.Hash == target.Hash {
+			break
+		}
+		if getTitleFromCommit(commit.Message) == title {
+			return fixupPrefix + shortHash(target.Hash.String())
+		}
+	}
+	return fixupPrefix + title
+}
+
+// Move each fixup commit right after the commit it fixes, and mark it to be squashed into it
+//
+// Steps are oldest first. A fixup commit fixes the latest commit before it whose title is its title
+// without the prefix, or whose hash starts with it. Fixups of fixups go into the original commit.
+// Fixup commits without a target are left as they are. Return the new steps and the number of fixups moved
+func planAutosquash(steps []rewriteStep) ([]rewriteStep, int) {
+	// Fixups of each target, by hash of the target
+	fixups := map[string][]rewriteStep{}
+	var kept []rewriteStep
+	matched := 0
+	for _, step := range steps {
+		if !strings.HasPrefix(step.Title, fixupPrefix) {
+			kept = append(kept, step)
+			continue
+		}
+		target := step.Title
+		for strings.HasPrefix(target, fixupPrefix) {
+			target = strings.TrimPrefix(target, fixupPrefix)
+		}
+		found := ""
+		for i := len(kept) - 1; i >= 0; i-- {
+			if kept[i].Title == target || (len(target) >= 4 && strings.HasPrefix(kept[i].Hash, target)) {
+				found = kept[i].Hash
+				break
+			}
+		}
+		if found == "" {
+			kept = append(kept, step)
+			continue
+		}
+		step.Action = rewriteFixup
+		fixups[found] = append(fixups[found], step)
+		matched++
+	}
+
+	result := make([]rewriteStep, 0, len(steps))
+	for _, step := range kept {
+		result = append(result, step)
+		result = append(result, fixups[step.Hash]...)
+	}
+	return result, matched
+}
+
+// Squash every fixup commit that isn't pushed into the commit it fixes
+//
+// The other commits keep their order
+func autosquash(wd string, protectionTrailer string) {
+	clean, err := executor.IsWorkTreeClean(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't check if there are uncommitted changes", err)
+	}
+	if !clean {
+		exitOnKnownError(errorWorkingTreeNotClean, nil)
+	}
+
+	commits := listUnpushedCommits(wd)
+	steps := make([]rewriteStep, len(commits))
+	for i, commit := range commits {
+		steps[len(commits)-1-i] = rewriteStep{
+			Hash:   commit.Hash.String(),
+			Title:  getTitleFromCommit(commit.Message),
+			Action: rewritePick,
+		}
+	}
+	steps, matched := planAutosquash(steps)
+	if matched == 0 {
+		print.Message("There is no fixup commit to squash. Create one with gut save --fixup", print.Info)
+		return
+	}
+	for _, step := range steps {
+		if step.Action == rewritePick && strings.HasPrefix(step.Title, fixupPrefix) {
+			print.Message("I can't find the commit %s fixes, so I'll leave it as is", print.Warning, step.Hash[:7])
+		}
+	}
+
+	print.Message("I'll squash %d fixup commit(s). History after the squash (newest first):", print.Info, matched)
+	for _, line := range previewRewrite(steps) {
+		fmt.Fprintf(color.Output, "\t%s\n", line)
+	}
+	res, err := prompt.InputBool("Do you want to continue?", true)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't squash anything", print.Info)
+		return
+	}
+	startRewrite(wd, commits[len(commits)-1].ParentHashes[0].String(), steps, protectionTrailer)
+}
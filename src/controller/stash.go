@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// Describe a stash entry on one line (e.g. stash@{0} on main: message (2 hours ago), 3 files)
+func formatStashEntry(entry executor.StashEntry, now time.Time) string {
+	description := entry.Name()
+	if entry.Branch != "" {
+		description += " on " + entry.Branch
+	}
+	description += ": " + entry.Message + " (" + formatAge(entry.When, now) + ")"
+	files, err := executor.GitStashFiles(entry)
+	if err == nil {
+		description += fmt.Sprintf(", %d file(s)", len(files))
+	}
+	return description
+}
+
+// Pick a stash entry from the argument (e.g. 2 or stash@{2}) or with a prompt
+func chooseStash(path string, args []string, question string) executor.StashEntry {
+	entries, err := executor.ListStashes(path)
+	if err != nil {
+		exitOnError("Sorry, I can't read the stash 😢", err)
+	}
+	if len(entries) == 0 {
+		print.Message("The stash is empty. Stash your changes with gut stash", print.Info)
+		os.Exit(0)
+	}
+	if len(args) > 0 {
+		index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(args[0], "stash@{"), "}"))
+		if err != nil || index < 0 || index >= len(entries) {
+			print.Message("I can't find the stash entry %s", print.Error, args[0])
+			os.Exit(1)
+		}
+		return entries[index]
+	}
+
+	now := time.Now()
+	options := make([]string, len(entries))
+	for i, entry := range entries {
+		options[i] = formatStashEntry(entry, now)
+	}
+	res, err := prompt.InputSelect(question, options)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	for i, option := range options {
+		if option == res {
+			return entries[i]
+		}
+	}
+	return executor.StashEntry{}
+}
+
+// Tell the user which files conflict after applying a stash entry, and how to recover
+func reportStashConflicts(entry string) {
+	print.Message("I couldn't restore your changes without conflicts 😓", print.Error)
+	conflicts, err := executor.GitListConflicts()
+	if err == nil && len(conflicts) > 0 {
+		print.Message("These files have conflicts:", print.None)
+		for _, file := range conflicts {
+			print.Message("\t%s", print.None, file)
+		}
+	}
+	print.Message("Nothing is lost: your changes are still saved in the stash (%s)", print.Info, entry)
+	print.Message("Fix the conflicts, then run git stash drop %s to forget the saved copy", print.Optional, entry)
+	print.Message("Or discard the changes with gut reset and try again with git stash apply %s", print.Optional, entry)
+}
+
+// Stash the uncommitted changes with a message
+func Stash(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+
+	message := strings.Join(args, " ")
+	if message == "" {
+		res, err := prompt.InputLine("What are these changes about? ")
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		message = res
+	}
+	untracked, _ := cmd.Flags().GetBool("untracked")
+	stashed, err := executor.GitStashPush(message, untracked)
+	if err != nil {
+		exitOnError("Sorry, I can't stash your changes 😢", err)
+	}
+	if !stashed {
+		print.Message("There is nothing to stash", print.Info)
+		if !untracked {
+			print.Message("New files are only stashed with gut stash --untracked", print.Optional)
+		}
+		return
+	}
+	print.Message("I've stashed your changes 🎉", print.Success)
+	print.Message("Get them back with gut stash pop", print.Optional)
+}
+
+// List the entries of the stash
+func StashList(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	entries, err := executor.ListStashes(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the stash 😢", err)
+	}
+	if len(entries) == 0 {
+		print.Message("The stash is empty", print.Info)
+		return
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		print.Message("%s", print.None, formatStashEntry(entry, now))
+	}
+}
+
+// Print the diff of a stash entry
+func StashShow(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	entry := chooseStash(wd, args, "Which entry do you want to see?")
+	err := executor.GitStashShow(entry)
+	if err != nil {
+		exitOnError("Sorry, I can't show the stash entry 😢", err)
+	}
+}
+
+// Apply a stash entry and keep it in the stash
+func StashApply(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	entry := chooseStash(wd, args, "Which entry do you want to apply?")
+	err := executor.GitStashApply(entry)
+	if err != nil {
+		reportStashConflicts(entry.Name())
+		os.Exit(1)
+	}
+	print.Message("I've applied %s. It's still in the stash", print.Success, entry.Name())
+}
+
+// Apply a stash entry and remove it from the stash
+func StashPop(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	entry := chooseStash(wd, args, "Which entry do you want to restore?")
+	err := executor.GitStashPopEntry(entry)
+	if err != nil {
+		reportStashConflicts(entry.Name())
+		os.Exit(1)
+	}
+	print.Message("I've restored your changes and removed them from the stash 🎉", print.Success)
+}
+
+// Remove a stash entry
+func StashDrop(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	entry := chooseStash(wd, args, "Which entry do you want to remove?")
+	res, err := prompt.InputBool("The changes of "+entry.Name()+" will be lost. Do you want to continue?", false)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't remove it", print.Info)
+		return
+	}
+	err = executor.GitStashDrop(entry)
+	if err != nil {
+		exitOnError("Sorry, I can't remove the stash entry 😢", err)
+	}
+	print.Message("I've removed %s", print.Success, entry.Name())
+}
+
+// Remove all the entries of the stash
+func StashClear(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	entries, err := executor.ListStashes(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the stash 😢", err)
+	}
+	if len(entries) == 0 {
+		print.Message("The stash is already empty", print.Info)
+		return
+	}
+	res, err := prompt.InputBool(fmt.Sprintf("The changes of the %d stash entries will be lost. Do you want to continue?", len(entries)), false)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't clear the stash", print.Info)
+		return
+	}
+	err = executor.GitStashClear()
+	if err != nil {
+		exitOnError("Sorry, I can't clear the stash 😢", err)
+	}
+	print.Message("I've cleared the stash", print.Success)
+}
+
+// Create a branch from a stash entry
+//
+// The branch starts from the commit the changes were stashed on, so they apply without conflict
+func StashBranch(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+	var entryArgs []string
+	if len(args) > 1 {
+		entryArgs = args[1:]
+	}
+	entry := chooseStash(wd, entryArgs, "Which entry do you want to turn into a branch?")
+
+	var branchName string
+	if len(args) > 0 {
+		branchName = args[0]
+	} else {
+		res, err := prompt.InputLine("Name of the new branch: ")
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		branchName = res
+	}
+	if executor.ValidateBranchName(branchName) != nil {
+		exitOnError(branchName+" isn't a valid branch name", nil)
+	}
+	branchName = enforceBranchConvention(wd, branchName)
+	exists, err := executor.CheckIfBranchExists(wd, branchName)
+	if err != nil {
+		exitOnError("I can't check if the branch exists", err)
+	}
+	if exists {
+		print.Message("The branch %s already exists", print.Error, branchName)
+		os.Exit(1)
+	}
+
+	previousBranch, _ := executor.GetCurrentBranch(wd)
+	err = executor.GitStashBranch(entry, branchName)
+	if err != nil {
+		// The branch is created before the changes are applied
+		if currentBranch, _ := executor.GetCurrentBranch(wd); currentBranch != branchName {
+			exitOnError("Sorry, I can't create the branch from the stash 😢", err)
+		}
+		recordCheckout(wd, previousBranch, branchName)
+		print.Message("I've created the branch %s", print.Info, branchName)
+		reportStashConflicts(entry.Name())
+		os.Exit(1)
+	}
+	recordCheckout(wd, previousBranch, branchName)
+	print.Message("You are now on the branch %s with the changes of %s 🎉", print.Success, branchName, entry.Name())
+}
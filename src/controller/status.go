@@ -47,6 +47,10 @@ func Status(cmd *cobra.Command, args []string) {
 	if err != nil {
 		exitOnError("Sorry, I can't get the status of the repository 😢", err)
 	}
+	// Submodules are listed at the end, even when there is no change
+	if executor.HasSubmodules(wd) {
+		defer printSubmodulesStatus(wd)
+	}
 	if len(status) == 0 {
 		fmt.Println("No changes to commit")
 		return
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/julien040/gut/src/executor"
+	"github.com/julien040/gut/src/print"
+	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/prompt"
+)
+
+// Profiles used to download the submodules, by host
+//
+// Submodules are often hosted on the same website as the repository, so we reuse the profile of the host
+type submoduleProfiles map[string]profile.Profile
+
+// Create the profiles of the submodules from the profile of the repository
+func newSubmoduleProfiles(path string, repoProfile profile.Profile) submoduleProfiles {
+	profiles := submoduleProfiles{}
+	if repoProfile.Id == "" {
+		return profiles
+	}
+	repoURL, err := executor.GetGitURL(path)
+	if err == nil && getHost(repoURL) != "" {
+		profiles[getHost(repoURL)] = repoProfile
+	}
+	return profiles
+}
+
+// Return the host of the submodule
+//
+// Relative URLs (e.g. ../lib.git) are resolved against the origin of the repository
+func getSubmoduleHost(path string, url string) string {
+	if strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../") {
+		repoURL, err := executor.GetGitURL(path)
+		if err != nil {
+			return ""
+		}
+		return getHost(repoURL)
+	}
+	return getHost(url)
+}
+
+// Return a profile created for the host if there is only one
+func findProfileForHost(host string) (profile.Profile, bool) {
+	var found []profile.Profile
+	for _, p := range *profile.GetProfiles() {
+		if p.Website == host {
+			found = append(found, p)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return profile.Profile{}, false
+}
+
+// Download the submodule and check out the commit recorded
+//
+// If the host requires credentials, we try the profile of the host and ask the user to select one if it's wrong
+func updateSubmodule(path string, submodule executor.Submodule, profiles submoduleProfiles) error {
+	host := getSubmoduleHost(path, submodule.URL)
+	current, known := profiles[host]
+	err := runWithProgress("Updating "+submodule.Path, func(ctx context.Context) error {
+		return executor.UpdateSubmodule(ctx, path, submodule.Name, current.Username, current.Password)
+	})
+	if isAuthError(err) {
+		print.Message("The submodule %s requires credentials for %s", print.Info, submodule.Path, host)
+		next, found := findProfileForHost(host)
+		if !found || (known && next.Id == current.Id) {
+			next = selectProfile(submodule.URL, true)
+		}
+		profiles[host] = next
+		return updateSubmodule(path, submodule, profiles)
+	}
+	return err
+}
+
+// Check out the commits recorded for the submodules, after a clone, a switch or a sync
+//
+// Submodules with local changes are left untouched and the user is told how to update them
+func syncSubmodules(path string, repoProfile profile.Profile) {
+	if !executor.HasSubmodules(path) {
+		return
+	}
+	submodules, err := executor.ListSubmodules(path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the submodules 😢", err)
+	}
+	profiles := newSubmoduleProfiles(path, repoProfile)
+	updated := 0
+	for _, submodule := range submodules {
+		if submodule.IsUpToDate() {
+			continue
+		}
+		clean, err := executor.IsSubmoduleClean(path, submodule)
+		if err != nil {
+			exitOnError("Sorry, I can't check if the submodule "+submodule.Path+" has uncommitted changes 😢", err)
+		}
+		if !clean {
+			print.Message("The submodule %s has uncommitted changes, so I didn't check out the commit recorded (%s)", print.Warning, submodule.Path, submodule.Expected[:7])
+			print.Message("Save them (cd %s && gut save) or discard them, then run gut submodule update", print.Optional, submodule.Path)
+			continue
+		}
+		err = updateSubmodule(path, submodule, profiles)
+		exitIfCancelled(err)
+		if err != nil {
+			print.Message("I can't update the submodule %s 😢: %s", print.Error, submodule.Path, err.Error())
+			continue
+		}
+		updated++
+	}
+	if updated > 0 {
+		print.Message("I've updated %d submodule(s) to the commits recorded", print.Success, updated)
+	}
+}
+
+// Print each submodule with the drift between the commit checked out and the commit recorded
+func printSubmodulesStatus(path string) {
+	submodules, err := executor.ListSubmodules(path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the submodules 😢", err)
+	}
+	if len(submodules) == 0 {
+		return
+	}
+	fmt.Println("\nSubmodules:")
+	for _, submodule := range submodules {
+		if !submodule.Initialized {
+			fmt.Fprintf(color.Output, "\t%s %s %s\n", color.HiBlackString("-"), submodule.Path, color.HiBlackString("not downloaded. Run gut submodule update"))
+			continue
+		}
+		line := fmt.Sprintf("\t%s %s %s", color.GreenString("✓"), submodule.Path, color.HiBlackString(submodule.Current[:7]))
+		if !submodule.IsUpToDate() {
+			line = fmt.Sprintf("\t%s %s %s", color.YellowString("±"), submodule.Path, color.YellowString(describeSubmoduleDrift(path, submodule)))
+		}
+		clean, err := executor.IsSubmoduleClean(path, submodule)
+		if err == nil && !clean {
+			line += color.RedString(" (uncommitted changes)")
+		}
+		fmt.Fprintln(color.Output, line)
+	}
+}
+
+// Describe how far the commit checked out in a submodule is from the commit recorded
+func describeSubmoduleDrift(path string, submodule executor.Submodule) string {
+	drift := fmt.Sprintf("checked out %s, recorded %s", submodule.Current[:7], submodule.Expected[:7])
+	subPath := filepath.Join(path, submodule.Path)
+	ahead, err := executor.ListCommitsNotIn(subPath, submodule.Current, submodule.Expected)
+	if err != nil {
+		// The recorded commit hasn't been downloaded yet
+		return drift
+	}
+	behind, err := executor.ListCommitsNotIn(subPath, submodule.Expected, submodule.Current)
+	if err != nil {
+		return drift
+	}
+	return fmt.Sprintf("%s (%d ahead, %d behind)", drift, len(ahead), len(behind))
+}
+
+// Return the profile of the repository without prompting. Empty if there is none
+func getRepoProfileIfAny(path string) profile.Profile {
+	repoProfile, err := profile.GetProfileFromPath(path)
+	if err != nil {
+		return profile.Profile{}
+	}
+	return repoProfile
+}
+
+// Pick a submodule from the argument or with a prompt
+func chooseSubmodule(path string, args []string) executor.Submodule {
+	submodules, err := executor.ListSubmodules(path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the submodules 😢", err)
+	}
+	if len(submodules) == 0 {
+		print.Message("This repository has no submodule", print.Warning)
+		os.Exit(0)
+	}
+	if len(args) > 0 {
+		for _, submodule := range submodules {
+			if submodule.Path == filepath.ToSlash(filepath.Clean(args[0])) || submodule.Name == args[0] {
+				return submodule
+			}
+		}
+		print.Message("I can't find the submodule %s", print.Error, args[0])
+		os.Exit(1)
+	}
+	var paths []string
+	for _, submodule := range submodules {
+		paths = append(paths, submodule.Path)
+	}
+	res, err := prompt.InputSelect("Which submodule?", paths)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	for _, submodule := range submodules {
+		if submodule.Path == res {
+			return submodule
+		}
+	}
+	return executor.Submodule{}
+}
+
+// Show the status of the submodules
+func SubmoduleStatus(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	if !executor.HasSubmodules(wd) {
+		print.Message("This repository has no submodule. Add one with gut submodule add <url>", print.Info)
+		return
+	}
+	printSubmodulesStatus(wd)
+}
+
+// Download the submodules and check out the commits recorded
+func SubmoduleUpdate(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	if !executor.HasSubmodules(wd) {
+		print.Message("This repository has no submodule", print.Info)
+		return
+	}
+	syncSubmodules(wd, getRepoProfileIfAny(wd))
+	printSubmodulesStatus(wd)
+}
+
+// Clone a repository in the working tree and register it as a submodule
+func SubmoduleAdd(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+
+	var url string
+	if len(args) > 0 {
+		url = args[0]
+	} else {
+		res, err := prompt.InputLine("URL of the repository to add: ")
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		url = res
+	}
+	if !checkURL(url) {
+		print.Message("This URL doesn't look valid 😓", print.Error)
+		os.Exit(1)
+	}
+	subPath := getRepoNameFromURL(url)
+	if len(args) > 1 {
+		subPath = args[1]
+	}
+	subPath = filepath.ToSlash(filepath.Clean(subPath))
+	if _, err := os.Stat(filepath.Join(wd, subPath)); err == nil {
+		print.Message("%s already exists. Choose another path with gut submodule add <url> <path>", print.Error, subPath)
+		os.Exit(1)
+	}
+
+	// We clone with go-git to reuse the profiles, then git only registers the clone
+	host := getHost(url)
+	subProfile, known := newSubmoduleProfiles(wd, getRepoProfileIfAny(wd))[host]
+	for {
+		err := runWithProgress("Cloning "+url, func(ctx context.Context) error {
+			if subProfile.Id == "" {
+				return executor.Clone(ctx, url, filepath.Join(wd, subPath), true)
+			}
+			return executor.CloneWithAuth(ctx, url, filepath.Join(wd, subPath), subProfile.Username, subProfile.Password, true)
+		})
+		if err == nil {
+			break
+		}
+		os.RemoveAll(filepath.Join(wd, subPath))
+		if !isAuthError(err) {
+			exitOnError("Sorry, I can't clone the submodule 😓", err)
+		}
+		print.Message("This repository requires credentials for %s", print.Info, host)
+		next, found := findProfileForHost(host)
+		if !found || (known && next.Id == subProfile.Id) {
+			next = selectProfile(url, true)
+		}
+		subProfile, known = next, true
+	}
+
+	err := executor.GitSubmoduleAdd(url, subPath)
+	if err != nil {
+		exitOnError("Sorry, I can't register the submodule 😓", err)
+	}
+	print.Message("I've added the submodule %s 🎉", print.Success, subPath)
+	print.Message("Run gut save to record it in your repository", print.Optional)
+}
+
+// Unregister a submodule and delete its files
+func SubmoduleRemove(cmd *cobra.Command, args []string) {
+	wd := getWorkingDir()
+	checkIfGitRepoInitialized(wd)
+	checkIfGitInstalled()
+
+	submodule := chooseSubmodule(wd, args)
+	clean, err := executor.IsSubmoduleClean(wd, submodule)
+	if err != nil {
+		exitOnError("Sorry, I can't check if the submodule has uncommitted changes 😢", err)
+	}
+	question := "Are you sure you want to remove the submodule " + submodule.Path + "?"
+	if !clean {
+		question = "The submodule " + submodule.Path + " has uncommitted changes. They will be lost. Do you want to remove it anyway?"
+	}
+	res, err := prompt.InputBool(question, false)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !res {
+		print.Message("Okay, I won't remove the submodule", print.Info)
+		return
+	}
+
+	err = executor.GitSubmoduleRemove(wd, submodule)
+	if err != nil {
+		exitOnError("Sorry, I can't remove the submodule 😓", err)
+	}
+	print.Message("I've removed the submodule %s 🎉", print.Success, submodule.Path)
+	print.Message("Run gut save to record it in your repository", print.Optional)
+}
@@ -1,9 +1,14 @@
 package controller
 
 import (
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/briandowns/spinner"
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
@@ -20,14 +25,15 @@ func Switch(cmd *cobra.Command, args []string) {
 	// Check if the current directory is a git repository
 	checkIfGitRepoInitialized(wd)
 
+	// Remembered for gut switch -. Empty if the repository has no commit yet
+	previousBranch, _ := executor.GetCurrentBranch(wd)
+
 	// Input the branch name
 	var refArg string
 	if len(args) == 0 {
-		res, err := prompt.InputLine("Switch to: ")
-		if err != nil {
-			exitOnKnownError(errorReadInput, err)
-		}
-		refArg = res
+		refArg = chooseBranchToSwitch(wd)
+	} else if args[0] == "-" {
+		refArg = getPreviousBranch(wd)
 	} else {
 		refArg = args[0]
 	}
@@ -69,24 +75,56 @@ func Switch(cmd *cobra.Command, args []string) {
 			if err != nil {
 				exitOnError("I can't switch to the commit", err)
 			}
+			syncSubmodules(wd, getRepoProfileIfAny(wd))
 			print.Message("I've successfully switched to the commit %s", print.Info, commit.Hash.String())
 			return
 		}
 
-		res, err := prompt.InputBool("Uh oh, the branch doesn't exist. Do you want me to create it?", true)
-		if err != nil {
-			exitOnKnownError(errorReadInput, err)
-		}
-		if res {
-			err = executor.CreateBranch(wd, refArg)
+		// The branch might only exist on a remote (e.g. origin/feature)
+		fetch, _ := cmd.Flags().GetBool("fetch")
+		upstream, found := findRemoteBranchToTrack(wd, refArg, fetch)
+		if found {
+			err = executor.CreateTrackingBranch(wd, refArg, upstream)
 			if err != nil {
 				exitOnError("My bad, I can't create the branch", err)
 			}
+			print.Message("The branch exists on %s. I've created a local branch tracking %s", print.Info, upstream.Remote, upstream.String())
+			exists = true
 		} else {
-			print.Message("Okay, I won't create the branch", print.Info)
-			return
+			res, err := prompt.InputBool("Uh oh, the branch doesn't exist. Do you want me to create it?", true)
+			if err != nil {
+				exitOnKnownError(errorReadInput, err)
+			}
+			if res {
+				refArg = enforceBranchConvention(wd, refArg)
+				exists, err = executor.CheckIfBranchExists(wd, refArg)
+				if err != nil {
+					exitOnError("I can't check if the branch exists", err)
+				}
+				base := getNewBranchBase(wd)
+				if exists {
+					print.Message("The branch %s already exists, I'll switch to it", print.Info, refArg)
+				} else if base == "" {
+					err = executor.CreateBranch(wd, refArg)
+					if err != nil {
+						exitOnError("My bad, I can't create the branch", err)
+					}
+				} else {
+					err = executor.CreateBranchFrom(wd, refArg, base)
+					if err != nil {
+						exitOnError("My bad, I can't create the branch", err)
+					}
+					print.Message("I've created the branch %s from %s", print.Info, refArg, base)
+					// Checked out like an existing branch so that the uncommitted changes are handled
+					exists = true
+				}
+			} else {
+				print.Message("Okay, I won't create the branch", print.Info)
+				return
+			}
 		}
-	} else { // If the branch exists, switch to it
+	}
+	if exists { // If the branch exists, switch to it
 
 		// Check if the branch is the current branch
 		currentBranch, err := executor.GetCurrentBranch(wd)
@@ -102,9 +140,13 @@ func Switch(cmd *cobra.Command, args []string) {
 		s.Prefix = "Checking if the working tree is clean... "
 		s.Start()
 
-		// Set to true when a stash is created
-		// We need to pop the stash at the end of the function
+		// Set to true when the changes are stashed to be taken to the new branch
+		// We need to pop the stash once the branch is checked out
 		mustStashPop := false
+		// Set to true when the changes are left on the current branch
+		autostashed := false
+		// Snapshot of the changes discarded, if any
+		snapshot := ""
 
 		// Check if the working tree is clean
 		clean, err := executor.IsWorkTreeClean(wd)
@@ -112,44 +154,287 @@ func Switch(cmd *cobra.Command, args []string) {
 		if err != nil {
 			exitOnError("I can't check if there are uncommitted changes", err)
 		}
-		// If not clean, ask the user if he wants to continue because the changes might be lost
+		// If not clean, ask the user what to do with the changes
 		if !clean {
 			/*
-				Because the working tree is not clean, we have two options:
-					- stash the changes
+				Because the working tree is not clean, we have three options:
+					- leave the changes on the current branch and restore them when the user comes back
+					- take the changes to the new branch
 					- discard the changes
 
-				We will ask the user which option he wants to use
+				We will ask the user which option they want to use
 			*/
 			print.Message("Uh oh, there are uncommitted changes", print.Warning)
 
-			res, err := prompt.InputSelect("What do you want to do?", []string{"Keep the changes", "Discard the changes"})
+			keepThem := "Keep them on " + currentBranch + " until I come back"
+			takeThem := "Take them to " + refArg
+			discard := "Discard the changes"
+			options := []string{keepThem, takeThem, discard}
+			if currentBranch == "HEAD" {
+				// The changes can't be left on a detached HEAD
+				options = options[1:]
+			}
+			res, err := prompt.InputSelect("What do you want to do?", options)
 			if err != nil {
 				exitOnKnownError(errorReadInput, err)
 			}
 
-			// If he wants to keep the changes, we stash them
-			// Also, we set a flag so that we can pop the stash at the end of the function
-			if res == "Keep the changes" {
-				executor.GitStash()
-				mustStashPop = true
+			switch res {
+			case keepThem:
+				autostashed, err = executor.GitAutostash(currentBranch)
+				if err != nil {
+					exitOnError("I can't put your changes aside, so I didn't switch branches", err)
+				}
+			case takeThem:
+				mustStashPop, err = executor.GitStashPush("gut switch from "+currentBranch+" to "+refArg, true)
+				if err != nil {
+					exitOnError("I can't put your changes aside, so I didn't switch branches", err)
+				}
+			case discard:
+				snapshot = snapshotBeforeDiscard(wd, "gut switch from "+currentBranch+" to "+refArg)
 			}
 		}
 		s.Prefix = "Switching to the branch " + refArg + " "
 		s.Start()
-
 		err = executor.CheckoutBranch(wd, refArg)
+		s.Stop()
+		if err != nil {
+			// Give the changes back before exiting
+			if mustStashPop {
+				popStash()
+			}
+			if autostashed {
+				restoreAutostash(wd, currentBranch, false)
+			}
+			exitOnError("I can't switch to the branch "+refArg, err)
+		}
 
 		// If we stashed the changes, we need to pop the stash
 		if mustStashPop {
-			executor.GitStashPop()
+			popStash()
 		}
-		s.Stop()
-		if err != nil {
-			exitOnError("I can't switch to the branch "+refArg, err)
+		if autostashed {
+			print.Message("I've kept your changes on %s. I'll offer to restore them when you come back", print.Info, currentBranch)
 		}
+		printSnapshotRestore(snapshot)
+		restoreAutostash(wd, refArg, true)
+		syncSubmodules(wd, getRepoProfileIfAny(wd))
 
 	}
+	recordCheckout(wd, previousBranch, refArg)
 	print.Message(`I switched to the branch "`+refArg+`" successfully 🎉`, print.Success)
 
 }
+
+// Find the remote-tracking branch to track when switching to a branch that doesn't exist locally
+//
+// If fetch is true, the remotes are fetched first. If several remotes have the branch, the user chooses one
+func findRemoteBranchToTrack(path string, branch string, fetch bool) (executor.Upstream, bool) {
+	if fetch {
+		fetchAllRemotes(path)
+	}
+	matches, err := executor.FindRemoteBranches(path, branch)
+	if err != nil {
+		exitOnError("I can't look for the branch on the remotes", err)
+	}
+	switch len(matches) {
+	case 0:
+		return executor.Upstream{}, false
+	case 1:
+		return matches[0], true
+	}
+
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = match.String()
+	}
+	print.Message("Several remotes have a branch named %s", print.Info, branch)
+	res, err := prompt.InputSelect("Which one do you want to track?", names)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	for _, match := range matches {
+		if match.String() == res {
+			return match, true
+		}
+	}
+	return executor.Upstream{}, false
+}
+
+// Remember that we switched branches for gut switch -
+//
+// It's only a history, so the errors are ignored
+func recordCheckout(path string, from string, to string) {
+	// The name of HEAD when it's detached
+	if from == "HEAD" {
+		from = ""
+	}
+	executor.RecordCheckout(path, from, to)
+}
+
+// Return the branch checked out before the current one
+func getPreviousBranch(path string) string {
+	recent, err := executor.ListRecentBranches(path)
+	if err != nil {
+		exitOnError("I can't read the branches you've switched to", err)
+	}
+	currentBranch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("I can't get the current branch", err)
+	}
+	for _, branch := range recent {
+		if branch != currentBranch {
+			return branch
+		}
+	}
+	print.Message("I don't know which branch you were on before", print.Error)
+	os.Exit(1)
+	return ""
+}
+
+// Return true if the letters of pattern appear in text in the same order (e.g. fln matches feat/login)
+func fuzzyMatch(pattern string, text string) bool {
+	text = strings.ToLower(text)
+	for _, r := range strings.ToLower(pattern) {
+		if r == ' ' {
+			continue
+		}
+		index := strings.IndexRune(text, r)
+		if index == -1 {
+			return false
+		}
+		text = text[index+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// Ask the user which branch to switch to, from the most recently used
+//
+// The first option lets them type a branch name or a commit instead
+func chooseBranchToSwitch(path string) string {
+	branches, err := executor.ListBranches(path)
+	if err != nil {
+		exitOnError("I can't list the branches", err)
+	}
+	recent, err := executor.ListRecentBranches(path)
+	if err != nil {
+		exitOnError("I can't read the branches you've switched to", err)
+	}
+	// Recent branches first, then the others by date of their last commit
+	ordered := append([]string{}, recent...)
+	for _, branch := range branches {
+		if !slices.Contains(recent, branch) {
+			ordered = append(ordered, branch)
+		}
+	}
+	if len(ordered) == 0 {
+		res, err := prompt.InputLine("Switch to: ")
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		return res
+	}
+
+	currentBranch, _ := executor.GetCurrentBranch(path)
+	stashes, err := executor.CountStashesByBranch(path)
+	if err != nil {
+		exitOnError("I can't read the stash", err)
+	}
+	width := 0
+	for _, branch := range ordered {
+		width = max(width, len(branch))
+	}
+	const typeName = "Type a branch name or a commit"
+	options := []string{typeName}
+	now := time.Now()
+	for _, branch := range ordered {
+		option := fmt.Sprintf("%-*s", width, branch)
+		if commit, err := executor.GetBranchCommit(path, branch); err == nil {
+			option += fmt.Sprintf("  %s (%s)", getTitleFromCommit(commit.Message), formatAge(commit.Author.When, now))
+		}
+		if branch == currentBranch {
+			option += " [current]"
+			if clean, err := executor.IsWorkTreeClean(path); err == nil && !clean {
+				option += " [uncommitted changes]"
+			}
+		}
+		if _, found, err := executor.GetAutostash(path, branch); err == nil && found {
+			option += " [changes kept aside]"
+		}
+		if stashes[branch] > 0 {
+			option += fmt.Sprintf(" [%d stashed]", stashes[branch])
+		}
+		options = append(options, option)
+	}
+
+	var index int
+	err = survey.AskOne(&survey.Select{
+		Message:  "Switch to:",
+		Options:  options,
+		PageSize: 10,
+		Filter: func(filter string, value string, i int) bool {
+			// Typing a name is always possible
+			if i == 0 {
+				return true
+			}
+			return fuzzyMatch(filter, ordered[i-1])
+		},
+	}, &index)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if index > 0 {
+		return ordered[index-1]
+	}
+	res, err := prompt.InputLine("Switch to: ")
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	return res
+}
+
+// Apply the last stash entry and drop it
+//
+// If it conflicts, the entry is kept and the user is told how to recover
+func popStash() {
+	err := executor.GitStashPop()
+	if err != nil {
+		reportStashConflicts("stash@{0}")
+	}
+}
+
+// Restore the changes left on the branch by gut switch
+//
+// If ask is true, the user is asked first. If they conflict, they are moved to the stash
+func restoreAutostash(path string, branch string, ask bool) {
+	autostash, found, err := executor.GetAutostash(path, branch)
+	if err != nil {
+		exitOnError("I can't check if you left changes on "+branch, err)
+	}
+	if !found {
+		return
+	}
+	if ask {
+		print.Message("You left uncommitted changes on %s %s", print.Info, branch, formatAge(autostash.When, time.Now()))
+		res, err := prompt.InputBool("Do you want to restore them?", true)
+		if err != nil {
+			exitOnKnownError(errorReadInput, err)
+		}
+		if !res {
+			print.Message("Okay, I'll keep them aside. I'll ask again next time you switch to %s", print.Info, branch)
+			return
+		}
+	}
+	err = executor.GitApplyAutostash(autostash)
+	if err == nil {
+		print.Message("I've restored your changes 🎉", print.Success)
+		return
+	}
+	// Keep them in the stash so that they can be applied again with the usual commands
+	err = executor.GitMoveAutostashToStash(autostash)
+	if err != nil {
+		print.Message("Your changes are still saved in refs/gut/autostash/%s (%s)", print.Warning, branch, autostash.Hash[:7])
+		exitOnError("I can't restore your changes", err)
+	}
+	reportStashConflicts("stash@{0}")
+}
@@ -1,17 +1,20 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
 	"github.com/julien040/gut/src/profile"
+	"github.com/julien040/gut/src/prompt"
 
+	"github.com/fatih/color"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/spf13/cobra"
-
-	"github.com/briandowns/spinner"
 )
 
 func Sync(cmd *cobra.Command, args []string) {
@@ -37,11 +40,35 @@ func Sync(cmd *cobra.Command, args []string) {
 		exitOnKnownError(errorWorkingTreeNotClean, nil)
 	}
 
+	// Sync is also called by other commands (e.g. merge) that don't have the --all flag
+	// In this case, GetBool returns an error and we only sync the current branch
+	all, _ := cmd.Flags().GetBool("all")
+	if all {
+		syncAllBranches(wd)
+		return
+	}
+
+	// If the repository is mirrored, we pull from one remote and push to all of them
+	conf, err := profile.GetGutConf(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't read the .gut file 😢", err)
+	}
+	if len(conf.Sync.Push) > 0 {
+		syncMirrors(wd, conf.Sync)
+		return
+	}
+
+	branch, err := executor.GetCurrentBranch(wd)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+
 	// Get the remote
+	// If the branch tracks a remote branch, we use its remote
 	// If there is no remote, we ask the user to add one
 	// If there is more than one remote, we ask the user to select one
 	// If there is only one remote, we use it
-	remote, err := getRemote(wd)
+	remote, err := getRemoteForBranch(wd, branch)
 	if err != nil {
 		exitOnError("Sorry, I can't get the remote 😢", err)
 	}
@@ -50,33 +77,96 @@ func Sync(cmd *cobra.Command, args []string) {
 	syncRepo(wd, remote, false)
 }
 
+// Get the profile to use to sync the repository
+//
+// If requestProfile is true, or if no profile is associated with the path, we ask the user to select one
+func getSyncProfile(path string, requestProfile bool) profile.Profile {
+	// If it's not the first time syncRepo is called, we ask the user to select a profile
+	if requestProfile {
+		return selectProfile("", true)
+	}
+	// Else, we get the profile from the path
+	profilePath, err := profile.GetProfileFromPath(path)
+	if err != nil { // If there is no profile associated with the path, we ask the user to select one
+		return selectProfile("", true)
+	}
+	// Else, we use the profile associated with the path
+	return profilePath
+}
+
 func syncRepo(path string, remote executor.Remote, requestProfile bool) error {
+	branch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	// Checked before pulling, so that a protected branch is still pulled
+	if isPushProtected(path, branch) {
+		return pullAndPublish(path, remote, getSyncProfile(path, requestProfile), true, printPushProtected)
+	}
+	return pullAndPublish(path, remote, getSyncProfile(path, requestProfile), false, func(path string, branch string, profileLocal profile.Profile) {
+		push(remote, path, branch, profileLocal)
+	})
+}
+
+// Return true if a protection rule forbids pushing to the branch (changes must go through a pull request)
+//
+// With --override-protection, the override is printed and false is returned
+func isPushProtected(path string, branch string) bool {
+	if _, protected := findProtectionRule(path, branch, actionPush); !protected {
+		return false
+	}
+	if protectionOverridden {
+		checkBranchProtection(path, branch, actionPush)
+		return false
+	}
+	return true
+}
+
+// Tell the user the branch has been pulled but not pushed because it's protected
+func printPushProtected(path string, branch string, profileLocal profile.Profile) {
+	print.Message("%s is protected: changes must go through a pull request, so I've pulled it without pushing", print.Info, branch)
+	print.Message("Push your changes to another branch and open a pull request with gut merge", print.Optional)
+}
+
+// Function called once the branch has been pulled to push it
+type publishFunc func(path string, branch string, profileLocal profile.Profile)
+
+// Pull the current branch from the remote, then call publish to push it
+//
+// profileLocal is the profile used to pull. If it's wrong, the user is asked to select another one.
+// If pullOnly is true, the remote is never changed: a branch that has diverged isn't force-pushed
+func pullAndPublish(path string, remote executor.Remote, profileLocal profile.Profile, pullOnly bool, publish publishFunc) error {
 	/*
 		This must get refactored for better readability
 	*/
-	var profileLocal profile.Profile
+	branch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+	remoteBranch := executor.GetRemoteBranchName(path, remote.Name, branch)
 
-	// If it's not the first time syncRepo is called, we ask the user to select a profile
-	if requestProfile {
-		profileLocal = selectProfile("", true)
-	} else { // Else, we get the profile from the path
-		profilePath, err := profile.GetProfileFromPath(path)
-		if err != nil { // If there is no profile associated with the path, we ask the user to select one
-			profileLocal = selectProfile("", true)
-		} else { // Else, we use the profile associated with the path
-			profileLocal = profilePath
+	// If the history has been rewritten (e.g. with gut squash or gut fix), the branch has diverged
+	// from the remote branch as it was on the last fetch. Pulling would bring back the old commits
+	lease, err := executor.GetRemoteTrackingHash(path, remote.Name, remoteBranch)
+	if err == nil && hasRewrittenHistory(path, branch, lease) {
+		if pullOnly {
+			print.Message("%s has diverged from %s/%s (its history has been rewritten), so pulling would bring back the old commits", print.Warning, branch, remote.Name, remoteBranch)
+			print.Message("Run gut sync to choose which history to keep, or gut push to replace the remote branch with yours", print.Optional)
+			return nil
+		}
+		if syncRewrittenHistory(path, remote, branch, lease, profileLocal) {
+			return nil
 		}
 	}
+
 	// Once we have the profile, we pull the repository
-	spinnerPull := spinner.New(spinner.CharSets[9], 100)
-	spinnerPull.Prefix = "Pulling the repository from " + remote.Name + " "
-	spinnerPull.Start()
-	err := executor.Pull(path, remote.Name, profileLocal.Username, profileLocal.Password)
-	spinnerPull.Stop()
+	err = runWithProgress("Pulling "+remote.Name+"/"+remoteBranch, func(ctx context.Context) error {
+		return executor.Pull(ctx, path, remote.Name, branch, profileLocal.Username, profileLocal.Password)
+	})
 	// If the credentials are wrong, we ask the user to select another profile
 	if err == transport.ErrAuthorizationFailed || err == transport.ErrAuthenticationRequired {
 		print.Message("Uh oh, your credentials are wrong 😢. Please select another profile.", print.Error)
-		return syncRepo(path, remote, true)
+		return pullAndPublish(path, remote, getSyncProfile(path, true), pullOnly, publish)
 
 	} else if err == git.ErrNonFastForwardUpdate {
 		// Try to do Git Pull if installed
@@ -92,18 +182,16 @@ func syncRepo(path string, remote executor.Remote, requestProfile bool) error {
 				exitOnError("Sorry, I can't set the git config to rebase the merge", err)
 			}
 
-			err = executor.GitPull(profileLocal.Username, profileLocal.Password, remote.Url)
+			err = executor.GitPull(profileLocal.Username, profileLocal.Password, remote.Url, remoteBranch)
 			if err == nil {
 				print.Message("Pull successful 🎉", print.Success)
+				syncSubmodules(path, profileLocal)
 				// We then push
-				shouldReturn, returnValue := push(remote, path, profileLocal)
-				if shouldReturn {
-					return returnValue
-				}
+				publish(path, branch, profileLocal)
 
 			} else {
 				print.Message("Sorry, I can't pull the repository 😢", print.Error)
-				print.Message("If there is a conflict, you can use the git cli to resolve it. \nOr you can rebase if you want\n	git pull -r "+remote.Name+" [branch] "+" \n	git push "+remote.Name+" [branch] ", print.None)
+				print.Message("If there is a conflict, you can use the git cli to resolve it. \nOr you can rebase if you want\n	git pull -r "+remote.Name+" "+remoteBranch+" \n	git push "+remote.Name+" "+branch+" ", print.None)
 				os.Exit(1)
 			}
 		} else {
@@ -112,27 +200,27 @@ func syncRepo(path string, remote executor.Remote, requestProfile bool) error {
 			os.Exit(1)
 		}
 
-	} else if err == git.NoErrAlreadyUpToDate || err == transport.ErrEmptyRemoteRepository || err == nil { // If there is nothing to pull, we push
-		print.Message("Pull successful 🎉", print.Success)
+	} else if err == git.NoErrAlreadyUpToDate || err == transport.ErrEmptyRemoteRepository || err == executor.ErrRemoteBranchNotFound || err == nil { // If there is nothing to pull, we push
+		if err == executor.ErrRemoteBranchNotFound {
+			print.Message("The branch %s doesn't exist on %s yet. I'll publish it", print.Info, branch, remote.Name)
+		} else {
+			print.Message("Pull successful 🎉", print.Success)
+			syncSubmodules(path, profileLocal)
+		}
 
 		// If there is nothing to push, we exit
 		// If there is another unknown error, we exit
-		shouldReturn, returnValue := push(remote, path, profileLocal)
-		if shouldReturn {
-			return returnValue
-		}
+		publish(path, branch, profileLocal)
 	} else { // If there is another unknown error, we exit
 		exitOnError("Sorry, I can't pull the repository 😢", err)
 	}
 	return nil
 }
 
-func push(remote executor.Remote, path string, profileLocal profile.Profile) (bool, error) {
-	spinnerPush := spinner.New(spinner.CharSets[9], 100)
-	spinnerPush.Prefix = "Pushing the repository to " + remote.Name + " "
-	spinnerPush.Start()
-	err := executor.Push(path, remote.Name, profileLocal.Username, profileLocal.Password)
-	spinnerPush.Stop()
+func push(remote executor.Remote, path string, branch string, profileLocal profile.Profile) (bool, error) {
+	err := runWithProgress("Pushing the repository to "+remote.Name, func(ctx context.Context) error {
+		return executor.Push(ctx, path, remote.Name, branch, profileLocal.Username, profileLocal.Password)
+	})
 
 	if err == git.NoErrAlreadyUpToDate || err == nil {
 		print.Message("I've successfully synced your repository 🎉", print.Success)
@@ -143,3 +231,287 @@ func push(remote executor.Remote, path string, profileLocal profile.Profile) (bo
 	}
 	return false, nil
 }
+
+// Result of the sync of one branch by gut sync --all
+type branchSyncResult struct {
+	Branch   string
+	Upstream string
+	Message  string
+	Err      error
+}
+
+// Sync every local branch that tracks a remote branch
+//
+// Each branch is checked out, pulled (fast-forward only) and pushed.
+// Errors don't stop the sync of the other branches, they are reported in the summary
+func syncAllBranches(path string) {
+	branches, err := executor.ListTrackingBranches(path)
+	if err != nil {
+		exitOnError("Sorry, I can't list the branches 😢", err)
+	}
+	if len(branches) == 0 {
+		print.Message("None of your branches track a remote branch yet. Run gut sync on a branch to publish it", print.Warning)
+		return
+	}
+
+	currentBranch, err := executor.GetCurrentBranch(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the current branch 😢", err)
+	}
+
+	profileLocal := getSyncProfile(path, false)
+
+	var results []branchSyncResult
+	cancelled := false
+	for _, branch := range branches {
+		upstream, err := executor.GetUpstream(path, branch)
+		if err != nil {
+			results = append(results, branchSyncResult{Branch: branch, Err: err})
+			continue
+		}
+		result := branchSyncResult{Branch: branch, Upstream: upstream.String()}
+
+		// We can only pull into the branch checked out
+		if branch != currentBranch {
+			err = executor.CheckoutBranch(path, branch)
+			if err != nil {
+				result.Err = fmt.Errorf("can't switch to the branch: %w", err)
+				results = append(results, result)
+				continue
+			}
+		}
+
+		result.Message, result.Err = syncBranch(path, upstream.Remote, branch, &profileLocal)
+		results = append(results, result)
+		if errors.Is(result.Err, errTransferCancelled) {
+			cancelled = true
+			break
+		}
+	}
+
+	// Go back to the branch the user was on, even after Ctrl-C
+	err = executor.CheckoutBranch(path, currentBranch)
+	if err != nil {
+		exitOnError("Sorry, I can't switch back to the branch "+currentBranch+" 😢", err)
+	}
+	if cancelled {
+		synced := 0
+		for _, result := range results {
+			if result.Err == nil {
+				synced++
+			}
+		}
+		print.Message("\nOkay, I've cancelled the sync of %s and switched back to %s", print.Warning, results[len(results)-1].Branch, currentBranch)
+		print.Message("%d branch(es) had already been synced", print.Info, synced)
+		recordJournal()
+		os.Exit(1)
+	}
+	syncSubmodules(path, profileLocal)
+
+	printBranchSyncResults(results)
+}
+
+// Pull and push the branch checked out without any prompt except when the credentials are wrong
+//
+// The profile is updated if the user selects another one, so that the next branches use it.
+// Return a short message describing what happened
+func syncBranch(path string, remote string, branch string, profileLocal *profile.Profile) (string, error) {
+	label := "Syncing " + branch + " with " + remote
+	pullErr := runWithProgress(label, func(ctx context.Context) error {
+		return executor.Pull(ctx, path, remote, branch, profileLocal.Username, profileLocal.Password)
+	})
+	if pullErr == transport.ErrAuthorizationFailed || pullErr == transport.ErrAuthenticationRequired {
+		print.Message("Uh oh, your credentials are wrong for %s 😢. Please select another profile.", print.Error, remote)
+		*profileLocal = getSyncProfile(path, true)
+		return syncBranch(path, remote, branch, profileLocal)
+	}
+	if pullErr == git.ErrNonFastForwardUpdate {
+		return "", errors.New("the branch has diverged from the remote. Switch to it and run gut sync")
+	}
+	if pullErr != nil && pullErr != git.NoErrAlreadyUpToDate && pullErr != transport.ErrEmptyRemoteRepository && pullErr != executor.ErrRemoteBranchNotFound {
+		return "", pullErr
+	}
+
+	if _, protected := findProtectionRule(path, branch, actionPush); protected && !protectionOverridden {
+		return "pulled, not pushed because the branch is protected", nil
+	}
+
+	pushErr := runWithProgress(label, func(ctx context.Context) error {
+		return executor.Push(ctx, path, remote, branch, profileLocal.Username, profileLocal.Password)
+	})
+	if pushErr != nil && pushErr != git.NoErrAlreadyUpToDate {
+		return "", pushErr
+	}
+
+	if pullErr == git.NoErrAlreadyUpToDate && pushErr == git.NoErrAlreadyUpToDate {
+		return "already up to date", nil
+	}
+	return "synced", nil
+}
+
+func printBranchSyncResults(results []branchSyncResult) {
+	fmt.Println()
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(color.Output, "%s %s %s %s\n", color.RedString("✗"), result.Branch, color.HiBlackString(result.Upstream), color.RedString(result.Err.Error()))
+		} else {
+			fmt.Fprintf(color.Output, "%s %s %s %s\n", color.GreenString("✓"), result.Branch, color.HiBlackString(result.Upstream), color.HiBlackString(result.Message))
+		}
+	}
+	fmt.Println()
+	if failed == 0 {
+		print.Message("I've successfully synced your %d branches 🎉", print.Success, len(results))
+	} else {
+		print.Message("I've synced %d branches, %d failed", print.Warning, len(results)-failed, failed)
+		os.Exit(1)
+	}
+}
+
+// Return true if the local branch and the remote branch (as it was on the last fetch)
+// both have commits the other doesn't have
+func hasDivergedFromRemote(path string, lease string) bool {
+	head, err := executor.GetHeadHash(path)
+	if err != nil {
+		return false
+	}
+	// Local branch is ahead of the remote branch
+	ahead, err := executor.IsAncestor(path, lease, head)
+	if err != nil || ahead {
+		return false
+	}
+	// Local branch is behind the remote branch
+	behind, err := executor.IsAncestor(path, head, lease)
+	if err != nil || behind {
+		return false
+	}
+	return true
+}
+
+// Return true if the branch pointed to hash at some point, according to its reflog or to the journal of gut
+//
+// gut uses go-git for most operations, which doesn't write the reflogs, so both are needed
+func wasBranchTip(branch string, hash string, reflog []executor.ReflogEntry, journal []executor.JournalEntry) bool {
+	for _, entry := range reflog {
+		if entry.Old == hash || entry.New == hash {
+			return true
+		}
+	}
+	ref := "refs/heads/" + branch
+	for _, entry := range journal {
+		if entry.Before.Refs[ref] == hash || entry.After.Refs[ref] == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if the history of the branch has been rewritten since the last fetch (e.g. with gut squash or gut fix)
+//
+// The branch must have diverged from the remote branch, and the remote branch must be an earlier tip of the branch.
+// Otherwise, the remote branch has commits from someone else that must be integrated, not overwritten
+func hasRewrittenHistory(path string, branch string, lease string) bool {
+	if !hasDivergedFromRemote(path, lease) {
+		return false
+	}
+	reflog, err := executor.ReadReflog(path, "refs/heads/"+branch)
+	if err != nil {
+		return false
+	}
+	journal, err := executor.ReadJournal(path)
+	if err != nil {
+		return false
+	}
+	return wasBranchTip(branch, lease, reflog, journal)
+}
+
+// Ask the user how to sync a branch whose history has been rewritten since the last fetch
+//
+// Integrating the remote branch is the default. If they choose to overwrite it, the commits dropped
+// are confirmed first, then we push with a lease on the last fetched remote branch.
+// Return true if the sync is done and false if it should continue with a normal pull
+func syncRewrittenHistory(path string, remote executor.Remote, branch string, lease string, profileLocal profile.Profile) bool {
+	remoteBranch := executor.GetRemoteBranchName(path, remote.Name, branch)
+	head, err := executor.GetHeadHash(path)
+	if err != nil {
+		exitOnError("Sorry, I can't get the last commit of the branch 😢", err)
+	}
+	replaced, err := executor.ListCommitsNotIn(path, lease, head)
+	if err != nil {
+		exitOnError("Sorry, I can't list the commits of "+remote.Name+"/"+remoteBranch+" 😢", err)
+	}
+
+	print.Message("You've rewritten the history of %s (e.g. with gut squash or gut fix) since it was on %s/%s.", print.Warning, branch, remote.Name, remoteBranch)
+	print.Message("These commits of %s/%s are not in your branch:", print.None, remote.Name, remoteBranch)
+	printCommitList(replaced)
+
+	const (
+		integrate = "Integrate them in my branch (pull)"
+		overwrite = "Replace them with my history (I've rewritten it)"
+		cancel    = "Cancel"
+	)
+	res, err := prompt.InputSelect("What do you want to do?", []string{integrate, overwrite, cancel})
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	switch res {
+	case integrate:
+		return false
+	case cancel:
+		print.Message("Okay, I won't sync your branch", print.Info)
+		return true
+	}
+	confirm, err := prompt.InputBool(fmt.Sprintf("The %d commit(s) above will be removed from %s/%s. Continue?", len(replaced), remote.Name, remoteBranch), false)
+	if err != nil {
+		exitOnKnownError(errorReadInput, err)
+	}
+	if !confirm {
+		print.Message("Okay, I won't sync your branch", print.Info)
+		return true
+	}
+	checkBranchProtection(path, branch, actionRewrite)
+	checkBranchProtection(path, branch, actionPush)
+
+	err = runWithProgress("Pushing your history to "+remote.Name, func(ctx context.Context) error {
+		return executor.PushForceWithLease(ctx, path, remote.Name, branch, lease, profileLocal.Username, profileLocal.Password)
+	})
+	if err == transport.ErrAuthorizationFailed || err == transport.ErrAuthenticationRequired {
+		print.Message("Uh oh, your credentials are wrong 😢. Please select another profile.", print.Error)
+		return syncRewrittenHistory(path, remote, branch, lease, getSyncProfile(path, true))
+	} else if err == executor.ErrStaleLease {
+		refuseStaleLease(path, remote, remoteBranch, lease, profileLocal)
+	} else if err != nil && err != git.NoErrAlreadyUpToDate {
+		exitOnError("Sorry, I can't push the repository 😢", err)
+	}
+	print.Message("I've replaced %s/%s with your history 🎉", print.Success, remote.Name, remoteBranch)
+	return true
+}
+
+// Explain to the user that someone pushed to the remote branch since the last fetch
+// and show the commits that would have been overwritten. Then exit
+func refuseStaleLease(path string, remote executor.Remote, remoteBranch string, lease string, profileLocal profile.Profile) {
+	print.Message("Someone pushed to %s/%s since your last sync. I won't overwrite their work.", print.Error, remote.Name, remoteBranch)
+
+	// We fetch to show what has been pushed
+	err := runWithProgress("Fetching "+remote.Name, func(ctx context.Context) error {
+		return executor.Fetch(ctx, path, remote.Name, false, profileLocal.Username, profileLocal.Password)
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		exitOnError("Sorry, I can't fetch the remote to show you what has been pushed 😢", err)
+	}
+	newTip, err := executor.GetRemoteTrackingHash(path, remote.Name, remoteBranch)
+	if err != nil {
+		exitOnError("Sorry, I can't find "+remote.Name+"/"+remoteBranch+" 😢", err)
+	}
+	pushed, err := executor.ListCommitsNotIn(path, newTip, lease)
+	if err != nil {
+		exitOnError("Sorry, I can't list the commits pushed 😢", err)
+	}
+	if len(pushed) > 0 {
+		print.Message("These commits would have been overwritten:", print.None)
+		printCommitList(pushed)
+	}
+	print.Message("Integrate them in your branch first (e.g. git pull --rebase %s %s), then run gut sync again", print.Optional, remote.Name, remoteBranch)
+	os.Exit(1)
+}
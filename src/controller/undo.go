@@ -2,6 +2,7 @@
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/julien040/gut/src/executor"
 	"github.com/julien040/gut/src/print"
@@ -36,11 +37,13 @@ func Undo(cmd *cobra.Command, args []string) {
 			print.Message("Ok, I won't do anything", print.Info)
 			return
 		}
+		snapshot := snapshotBeforeDiscard(wd, "gut reset")
 		err = executor.GitResetAllHead()
 		if err != nil {
 			exitOnError("Sorry, I can't revert your working tree to the last commit", err)
 		}
 		print.Message("I've successfully reverted your working tree to the last commit 🎉", print.Success)
+		printSnapshotRestore(snapshot)
 		return
 	} else {
 		var resetFiles []string
@@ -69,6 +72,7 @@ func Undo(cmd *cobra.Command, args []string) {
 			return
 		}
 
+		snapshot := snapshotBeforeDiscard(wd, "gut reset "+strings.Join(resetFiles, " "))
 		for _, val := range resetFiles {
 			err = executor.GitCheckoutFileHead(val)
 			if err == nil {
@@ -78,6 +82,7 @@ func Undo(cmd *cobra.Command, args []string) {
 			}
 
 		}
+		printSnapshotRestore(snapshot, resetFiles...)
 		return
 
 	}
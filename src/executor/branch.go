@@ -2,12 +2,33 @@
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
+// The remote branch a local branch is tracking
+//
+// It's stored in the git config as branch.<name>.remote and branch.<name>.merge
+type Upstream struct {
+	Remote string
+	Branch string
+}
+
+// Return the name of the upstream as shown to the user (e.g. origin/main)
+func (u Upstream) String() string {
+	if u.Remote == "" {
+		return ""
+	}
+	return u.Remote + "/" + u.Branch
+}
+
 func ListBranches(path string) ([]string, error) {
 	repo, err := OpenRepo(path)
 	if err != nil {
@@ -72,6 +93,36 @@ func CreateBranch(path string, branchName string) error {
 	return err
 }
 
+// Create a branch pointing to the last commit of another local branch, without checking it out
+func CreateBranchFrom(path string, branchName string, base string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(branchName), false)
+	if err == nil {
+		return errors.New("branch already exists")
+	}
+	baseRef, err := repo.Reference(plumbing.NewBranchReferenceName(base), true)
+	if err != nil {
+		return err
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branchName), baseRef.Hash()))
+}
+
+// Create a branch pointing to a commit, without checking it out
+func CreateBranchAt(path string, branchName string, hash string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(branchName), false)
+	if err == nil {
+		return errors.New("branch already exists")
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branchName), plumbing.NewHash(hash)))
+}
+
 func CheckoutBranch(path string, branchName string) error {
 	repo, err := OpenRepo(path)
 	if err != nil {
@@ -114,7 +165,37 @@ func DeleteBranch(path string, branchName string) error {
 
 	branch := plumbing.NewBranchReferenceName(branchName)
 	err = repo.Storer.RemoveReference(branch)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Remove the upstream of the branch from the config like git branch -d
+	conf, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if _, ok := conf.Branches[branchName]; ok {
+		delete(conf.Branches, branchName)
+		return repo.SetConfig(conf)
+	}
+	return nil
+}
+
+// Get the last commit of a local branch
+func GetBranchCommit(path string, branchName string) (object.Commit, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return object.Commit{}, err
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branchName), true)
+	if err != nil {
+		return object.Commit{}, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return object.Commit{}, err
+	}
+	return *commit, nil
 }
 
 func CheckIfBranchExists(path string, branchName string) (bool, error) {
@@ -157,3 +238,320 @@ func GetCurrentBranch(path string) (string, error) {
 	}
 	return head.Name().Short(), nil
 }
+
+// Get the upstream of a local branch
+//
+// Return an empty Upstream if the branch doesn't track any remote branch
+func GetUpstream(path string, branchName string) (Upstream, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return Upstream{}, err
+	}
+	conf, err := repo.Config()
+	if err != nil {
+		return Upstream{}, err
+	}
+	branchConf, ok := conf.Branches[branchName]
+	if !ok || branchConf.Remote == "" || branchConf.Merge == "" {
+		return Upstream{}, nil
+	}
+	return Upstream{
+		Remote: branchConf.Remote,
+		Branch: branchConf.Merge.Short(),
+	}, nil
+}
+
+// Set branch.<name>.remote and branch.<name>.merge so that the local branch tracks remoteBranch on remote
+func SetUpstream(path string, branchName string, remote string, remoteBranch string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	conf, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	branchConf, ok := conf.Branches[branchName]
+	if !ok {
+		branchConf = &config.Branch{Name: branchName}
+		conf.Branches[branchName] = branchConf
+	}
+	branchConf.Remote = remote
+	branchConf.Merge = plumbing.NewBranchReferenceName(remoteBranch)
+	return repo.SetConfig(conf)
+}
+
+// List the local branches that track a remote branch
+//
+// Branches are sorted like ListBranches (most recent first)
+func ListTrackingBranches(path string) ([]string, error) {
+	branches, err := ListBranches(path)
+	if err != nil {
+		return nil, err
+	}
+	var tracking []string
+	for _, branch := range branches {
+		upstream, err := GetUpstream(path, branch)
+		if err != nil {
+			return nil, err
+		}
+		if upstream.Remote != "" {
+			tracking = append(tracking, branch)
+		}
+	}
+	return tracking, nil
+}
+
+// Get the hash of the remote-tracking branch refs/remotes/<remote>/<remoteBranch>
+//
+// It's the state of the remote branch the last time it was fetched
+func GetRemoteTrackingHash(path string, remote string, remoteBranch string) (string, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return "", err
+	}
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, remoteBranch), true)
+	if err != nil {
+		return "", err
+	}
+	return ref.Hash().String(), nil
+}
+
+// Return an error if the name can't be used for a branch
+func ValidateBranchName(branchName string) error {
+	if branchName == "" || branchName == "HEAD" {
+		return errors.New("invalid branch name")
+	}
+	return plumbing.NewBranchReferenceName(branchName).Validate()
+}
+
+// Rename a local branch like git branch -m
+//
+// The reflog and the config (upstream) of the branch are moved to the new name.
+// If the branch is checked out, HEAD points to the new name
+func RenameBranch(path string, oldName string, newName string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	oldRef := plumbing.NewBranchReferenceName(oldName)
+	newRef := plumbing.NewBranchReferenceName(newName)
+
+	ref, err := repo.Reference(oldRef, false)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Reference(newRef, false)
+	if err == nil {
+		return errors.New("branch already exists")
+	}
+
+	// The old ref is removed first because feature can't be renamed to feature/x otherwise
+	err = repo.Storer.RemoveReference(oldRef)
+	if err != nil {
+		return err
+	}
+	gitDir := filepath.Join(path, ".git")
+	removeEmptyParents(filepath.Join(gitDir, filepath.FromSlash(oldRef.String())), filepath.Join(gitDir, "refs", "heads"))
+	err = repo.Storer.SetReference(plumbing.NewHashReference(newRef, ref.Hash()))
+	if err != nil {
+		// Restore the branch so that nothing is lost
+		repo.Storer.SetReference(ref)
+		return err
+	}
+
+	// Move HEAD if the branch is checked out
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return err
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target() == oldRef {
+		err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, newRef))
+		if err != nil {
+			return err
+		}
+	}
+
+	// go-git doesn't handle reflogs, so we move the file like git does
+	logsDir := filepath.Join(gitDir, "logs")
+	oldLog := filepath.Join(logsDir, filepath.FromSlash(oldRef.String()))
+	if _, err := os.Stat(oldLog); err == nil {
+		// Same as the refs, the old log might be a parent folder of the new one
+		tmpLog := filepath.Join(logsDir, "gut-rename.tmp")
+		err = os.Rename(oldLog, tmpLog)
+		if err != nil {
+			return err
+		}
+		removeEmptyParents(oldLog, filepath.Join(logsDir, "refs", "heads"))
+		newLog := filepath.Join(logsDir, filepath.FromSlash(newRef.String()))
+		err = os.MkdirAll(filepath.Dir(newLog), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(tmpLog, newLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	conf, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	if branchConf, ok := conf.Branches[oldName]; ok {
+		delete(conf.Branches, oldName)
+		branchConf.Name = newName
+		conf.Branches[newName] = branchConf
+		return repo.SetConfig(conf)
+	}
+	return nil
+}
+
+// Remove the empty folders containing file, up to stop (excluded)
+//
+// feature/x leaves an empty feature folder once deleted, which prevents creating the branch feature
+func removeEmptyParents(file string, stop string) {
+	for dir := filepath.Dir(file); dir != stop && strings.HasPrefix(dir, stop); dir = filepath.Dir(dir) {
+		// os.Remove fails if the folder isn't empty
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// Details about a local branch shown by gut branch
+type BranchInfo struct {
+	Name     string
+	Upstream Upstream
+	// The branch tracks a remote branch that doesn't exist anymore (deleted on the remote, then fetched with prune)
+	UpstreamGone bool
+	// Number of commits not pushed to the upstream
+	Ahead int
+	// Number of commits of the upstream not pulled
+	Behind     int
+	LastCommit object.Commit
+	// The branch has been merged into the default branch
+	Merged bool
+}
+
+// Return the default branch of the repository (e.g. main)
+//
+// It's the branch origin/HEAD points to. If origin/HEAD doesn't exist, we look for main, then master.
+// Return an empty string if there is no default branch
+func GetDefaultBranch(path string) (string, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return "", err
+	}
+	remoteHead, err := repo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), false)
+	if err == nil && remoteHead.Type() == plumbing.SymbolicReference {
+		// refs/remotes/origin/main => main
+		name := strings.TrimPrefix(remoteHead.Target().String(), "refs/remotes/origin/")
+		if _, err := repo.Reference(plumbing.NewBranchReferenceName(name), false); err == nil {
+			return name, nil
+		}
+	}
+	for _, name := range []string{"main", "master"} {
+		if _, err := repo.Reference(plumbing.NewBranchReferenceName(name), false); err == nil {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// Get the details of a local branch
+//
+// defaultBranch is used to know if the branch has been merged. It can be empty
+func GetBranchInfo(path string, branchName string, defaultBranch string) (BranchInfo, error) {
+	info := BranchInfo{Name: branchName}
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return info, err
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branchName), false)
+	if err != nil {
+		return info, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return info, err
+	}
+	info.LastCommit = *commit
+
+	info.Upstream, err = GetUpstream(path, branchName)
+	if err != nil {
+		return info, err
+	}
+	if info.Upstream.Remote != "" {
+		upstreamHash, err := GetRemoteTrackingHash(path, info.Upstream.Remote, info.Upstream.Branch)
+		if err == plumbing.ErrReferenceNotFound {
+			info.UpstreamGone = true
+		} else if err != nil {
+			return info, err
+		} else {
+			info.Ahead, info.Behind, err = CountAheadBehind(path, ref.Hash().String(), upstreamHash)
+			if err != nil {
+				return info, err
+			}
+		}
+	}
+
+	if defaultBranch != "" && defaultBranch != branchName {
+		defaultRef, err := repo.Reference(plumbing.NewBranchReferenceName(defaultBranch), false)
+		if err != nil {
+			return info, err
+		}
+		info.Merged, err = IsAncestor(path, ref.Hash().String(), defaultRef.Hash().String())
+		if err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// Find the remote-tracking branches named branchName (e.g. origin/feature and upstream/feature for feature)
+func FindRemoteBranches(path string, branchName string) ([]Upstream, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	var found []Upstream
+	for _, remote := range remotes {
+		name := remote.Config().Name
+		_, err := repo.Reference(plumbing.NewRemoteReferenceName(name, branchName), false)
+		if err == nil {
+			found = append(found, Upstream{Remote: name, Branch: branchName})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Remote < found[j].Remote
+	})
+	return found, nil
+}
+
+// Create a local branch at the commit of a remote-tracking branch and make it track it
+//
+// The branch is not checked out
+func CreateTrackingBranch(path string, branchName string, upstream Upstream) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(branchName), false)
+	if err == nil {
+		return errors.New("branch already exists")
+	}
+	hash, err := GetRemoteTrackingHash(path, upstream.Remote, upstream.Branch)
+	if err != nil {
+		return err
+	}
+	err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branchName), plumbing.NewHash(hash)))
+	if err != nil {
+		return err
+	}
+	return SetUpstream(path, branchName, upstream.Remote, upstream.Branch)
+}
@@ -0,0 +1,26 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Apply the commits on the current branch, in the order given
+//
+// go-git can't cherry-pick, so the git CLI is used. If a commit can't be applied, an error is returned
+// and the cherry-pick stays in progress: call GitCherryPickAbort to restore the branch
+func GitCherryPick(hashes ...string) error {
+	args := append([]string{"git", "cherry-pick", "--allow-empty"}, hashes...)
+	return runCommand(args...)
+}
+
+// Stop the cherry-pick in progress and restore the branch as it was before it
+func GitCherryPickAbort() error {
+	return runCommand("git", "cherry-pick", "--abort")
+}
+
+// Return true if a cherry-pick is in progress (e.g. stopped because of conflicts)
+func IsCherryPicking(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git", "CHERRY_PICK_HEAD"))
+	return err == nil
+}
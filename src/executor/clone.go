@@ -1,37 +1,44 @@
 package executor
 
 import (
+	"context"
+
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 
 	"github.com/go-git/go-git/v5"
 )
 
-func Clone(repo string, path string, oldCommitSave bool) error {
+// Clone a repository
+//
+// The progress carried by ctx is updated and the clone is aborted when ctx is cancelled
+func Clone(ctx context.Context, repo string, path string, oldCommitSave bool) error {
 	options := &git.CloneOptions{
-		URL: repo,
+		URL:      repo,
+		Progress: sidebandProgress(ctx),
 	}
 	if !oldCommitSave {
 		options.Depth = 1
 	}
-	_, err := git.PlainClone(path, false, options)
+	_, err := git.PlainCloneContext(ctx, path, false, options)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CloneWithAuth(repo string, path string, username string, password string, oldCommitSave bool) error {
+func CloneWithAuth(ctx context.Context, repo string, path string, username string, password string, oldCommitSave bool) error {
 	options := &git.CloneOptions{
 		URL: repo,
 		Auth: &http.BasicAuth{
 			Username: username,
 			Password: password,
 		},
+		Progress: sidebandProgress(ctx),
 	}
 	if !oldCommitSave {
 		options.Depth = 1
 	}
-	_, err := git.PlainClone(path, false, options)
+	_, err := git.PlainCloneContext(ctx, path, false, options)
 	if err != nil {
 		return err
 	}
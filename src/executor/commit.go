@@ -6,6 +6,7 @@
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -297,3 +298,93 @@ func CheckoutCommit(path string, commitHash string) error {
 	}
 	return nil
 }
+
+// Return true if ancestor is an ancestor of descendant (or the same commit)
+func IsAncestor(path string, ancestor string, descendant string) (bool, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return false, err
+	}
+	ancestorCommit, err := repo.CommitObject(plumbing.NewHash(ancestor))
+	if err != nil {
+		return false, err
+	}
+	descendantCommit, err := repo.CommitObject(plumbing.NewHash(descendant))
+	if err != nil {
+		return false, err
+	}
+	return ancestorCommit.IsAncestor(descendantCommit)
+}
+
+// List the commits reachable from tip that are not reachable from exclude
+//
+// It's the equivalent of git log exclude..tip. The newest commits come first
+func ListCommitsNotIn(path string, tip string, exclude string) ([]object.Commit, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	// Mark every commit reachable from exclude
+	excluded := map[plumbing.Hash]bool{}
+	excludeIter, err := repo.Log(&git.LogOptions{From: plumbing.NewHash(exclude)})
+	if err != nil {
+		return nil, err
+	}
+	err = excludeIter.ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tipIter, err := repo.Log(&git.LogOptions{From: plumbing.NewHash(tip)})
+	if err != nil {
+		return nil, err
+	}
+	commits := []object.Commit{}
+	err = tipIter.ForEach(func(c *object.Commit) error {
+		// A merge brings other parents after an excluded one, so the walk goes on
+		if excluded[c.Hash] {
+			return nil
+		}
+		commits = append(commits, *c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// Count the commits of a that aren't in b (ahead) and the commits of b that aren't in a (behind)
+//
+// git rev-list only walks the commits down to the merge base. Without git, the whole history is walked
+func CountAheadBehind(path string, a string, b string) (int, int, error) {
+	if !IsGitInstalled() {
+		ahead, err := ListCommitsNotIn(path, a, b)
+		if err != nil {
+			return 0, 0, err
+		}
+		behind, err := ListCommitsNotIn(path, b, a)
+		if err != nil {
+			return 0, 0, err
+		}
+		return len(ahead), len(behind), nil
+	}
+	output, err := runCommandQuiet("git", "rev-list", "--left-right", "--count", a+"..."+b)
+	if err != nil {
+		return 0, 0, err
+	}
+	// <ahead>\t<behind>
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, errors.New("unexpected output of git rev-list: " + output)
+	}
+	ahead, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	behind, err := strconv.Atoi(fields[1])
+	return ahead, behind, err
+}
@@ -0,0 +1,318 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestListCommitsNotIn(t *testing.T) {
+	wd := t.TempDir()
+	repo, err := git.PlainInit(wd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(message string, parents ...plumbing.Hash) plumbing.Hash {
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			AllowEmptyCommits: true,
+			Parents:           parents,
+			Author:            &object.Signature{Name: "gut", Email: "gut@localhost", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	// base - main ----- merge - tip
+	//     \            /
+	//      - feature -
+	base := commit("base")
+	main := commit("main", base)
+	feature := commit("feature", base)
+	merge := commit("merge", main, feature)
+	tip := commit("tip", merge)
+
+	tests := []struct {
+		name    string
+		tip     plumbing.Hash
+		exclude plumbing.Hash
+		want    []string
+	}{
+		{"linear", main, base, []string{"main"}},
+		{"merge in the range", tip, main, []string{"feature", "merge", "tip"}},
+		{"merged branch", tip, feature, []string{"main", "merge", "tip"}},
+		{"nothing new", main, tip, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := ListCommitsNotIn(wd, tt.tip.String(), tt.exclude.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, c := range commits {
+				got = append(got, c.Message)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListCommitsNotIn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Create an empty repository and make it the working directory, as the git cli runs there
+func newTestRepo(t *testing.T) string {
+	wd := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "gut")
+	}
+	for _, email := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(email, "gut@localhost")
+	}
+	runGit(t, "init", "-q", "-b", "main")
+	return wd
+}
+
+// Run a git command in the working directory and fail the test if it fails
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := runCommandQuiet(append([]string{"git"}, args...)...)
+	if err != nil {
+		t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return output
+}
+
+// Write the files of the working tree, or delete them if their content is empty
+func writeFiles(t *testing.T, wd string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		var err error
+		if content == "" {
+			err = os.Remove(filepath.Join(wd, name))
+		} else {
+			err = os.WriteFile(filepath.Join(wd, name), []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Read the files of the working tree. A missing file is read as an empty string
+func readFiles(t *testing.T, wd string, names ...string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(wd, name))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		files[name] = string(content)
+	}
+	return files
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	wd := newTestRepo(t)
+	writeFiles(t, wd, map[string]string{"a.txt": "a", "b.txt": "b"})
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "base")
+
+	ref, err := CreateSnapshot(wd, "clean")
+	if err != nil || ref != "" {
+		t.Errorf("CreateSnapshot() without changes = %q, %v, want no snapshot", ref, err)
+	}
+
+	writeFiles(t, wd, map[string]string{"a.txt": "a changed", "b.txt": "", "c.txt": "c"})
+	runGit(t, "add", "a.txt")
+	index := runGit(t, "write-tree")
+	ref, err = CreateSnapshot(wd, "changes")
+	if err != nil || !strings.HasPrefix(ref, snapshotPrefix) {
+		t.Fatalf("CreateSnapshot() = %q, %v", ref, err)
+	}
+	if got, want := runGit(t, "rev-parse", ref+"^"), runGit(t, "rev-parse", "HEAD"); got != want {
+		t.Errorf("parent of the snapshot = %s, want HEAD %s", got, want)
+	}
+	if got := runGit(t, "ls-tree", "--name-only", ref); got != "a.txt\nc.txt" {
+		t.Errorf("files of the snapshot = %q, want a.txt and c.txt", got)
+	}
+	if got := runGit(t, "show", ref+":a.txt"); got != "a changed" {
+		t.Errorf("a.txt in the snapshot = %q, want a changed", got)
+	}
+	// The index and the working tree are left as they were
+	if got := runGit(t, "write-tree"); got != index {
+		t.Errorf("index after the snapshot = %s, want %s", got, index)
+	}
+	want := map[string]string{"a.txt": "a changed", "b.txt": "", "c.txt": "c"}
+	if got := readFiles(t, wd, "a.txt", "b.txt", "c.txt"); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after the snapshot = %v, want %v", got, want)
+	}
+
+	// A second snapshot gets its own ref, even when taken in the same second
+	writeFiles(t, wd, map[string]string{"c.txt": "c changed"})
+	second, err := CreateSnapshot(wd, "more changes")
+	if err != nil || second == "" || second == ref {
+		t.Errorf("second CreateSnapshot() = %q, %v, want a ref other than %q", second, err, ref)
+	}
+}
+
+func TestSnapshotRestoreAfterSwitch(t *testing.T) {
+	wd := newTestRepo(t)
+	writeFiles(t, wd, map[string]string{"a.txt": "a", "b.txt": "b"})
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "base")
+	runGit(t, "checkout", "-q", "-b", "feature")
+	writeFiles(t, wd, map[string]string{"c.txt": "c"})
+	runGit(t, "add", "c.txt")
+	runGit(t, "commit", "-q", "-m", "add c")
+	runGit(t, "checkout", "-q", "main")
+
+	// Modified, deleted and untracked files
+	writeFiles(t, wd, map[string]string{"b.txt": "b changed", "a.txt": "", "d.txt": "d"})
+	ref, err := CreateSnapshot(wd, "test")
+	if err != nil || ref == "" {
+		t.Fatalf("CreateSnapshot() = %q, %v", ref, err)
+	}
+	runGit(t, "reset", "-q", "--hard")
+	runGit(t, "clean", "-q", "-fd")
+	runGit(t, "checkout", "-q", "feature")
+
+	runGit(t, strings.Fields(SnapshotRestoreCommand(ref))[1:]...)
+	want := map[string]string{"a.txt": "", "b.txt": "b changed", "c.txt": "c", "d.txt": "d"}
+	if got := readFiles(t, wd, "a.txt", "b.txt", "c.txt", "d.txt"); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after the restore = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreRepoState(t *testing.T) {
+	wd := newTestRepo(t)
+	writeFiles(t, wd, map[string]string{"a.txt": "a", "b.txt": "b"})
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "base")
+	runGit(t, "branch", "feature")
+	writeFiles(t, wd, map[string]string{"b.txt": "b stashed"})
+	runGit(t, "stash", "-q")
+
+	// A staged change, an unstaged change and an untracked file
+	writeFiles(t, wd, map[string]string{"a.txt": "a staged"})
+	runGit(t, "add", "a.txt")
+	writeFiles(t, wd, map[string]string{"b.txt": "b unstaged", "c.txt": "c"})
+	state, err := GetRepoState(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := runGit(t, "rev-parse", "main")
+	feature := runGit(t, "rev-parse", "feature")
+
+	// Move main, delete feature, create and check out other, and stash the changes
+	runGit(t, "commit", "-q", "-m", "staged")
+	runGit(t, "branch", "-q", "-D", "feature")
+	runGit(t, "checkout", "-q", "-b", "other")
+	runGit(t, "stash", "-q", "--include-untracked")
+	stash := runGit(t, "rev-parse", StashRef)
+
+	if err := RestoreRepoState(wd, state); err != nil {
+		t.Fatal(err)
+	}
+	if got := runGit(t, "symbolic-ref", "HEAD"); got != "refs/heads/main" {
+		t.Errorf("HEAD = %s, want refs/heads/main", got)
+	}
+	if got := runGit(t, "rev-parse", "main"); got != main {
+		t.Errorf("main = %s, want %s", got, main)
+	}
+	if got := runGit(t, "rev-parse", "feature"); got != feature {
+		t.Errorf("feature = %s, want %s", got, feature)
+	}
+	if got, _ := runCommandQuiet("git", "rev-parse", "--verify", "--quiet", "other"); got != "" {
+		t.Errorf("other = %s, want it deleted", got)
+	}
+	if got := runGit(t, "rev-parse", StashRef); got != stash {
+		t.Errorf("%s = %s, want %s left as it is", StashRef, got, stash)
+	}
+	if got := runGit(t, "stash", "list"); len(strings.Split(got, "\n")) != 2 {
+		t.Errorf("stash list = %q, want 2 entries", got)
+	}
+
+	want := map[string]string{"a.txt": "a staged", "b.txt": "b unstaged", "c.txt": "c"}
+	if got := readFiles(t, wd, "a.txt", "b.txt", "c.txt"); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after the restore = %v, want %v", got, want)
+	}
+	if got := runGit(t, "diff", "--cached", "--name-only"); got != "a.txt" {
+		t.Errorf("staged files = %q, want a.txt", got)
+	}
+	if got := runGit(t, "diff", "--name-only"); got != "b.txt" {
+		t.Errorf("unstaged files = %q, want b.txt", got)
+	}
+	if got := runGit(t, "ls-files", "--others"); got != "c.txt" {
+		t.Errorf("untracked files = %q, want c.txt", got)
+	}
+}
+
+func TestCountAheadBehind(t *testing.T) {
+	wd := newTestRepo(t)
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "base")
+	runGit(t, "checkout", "-q", "-b", "feature")
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "feature 1")
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "feature 2")
+	runGit(t, "checkout", "-q", "main")
+	runGit(t, "commit", "-q", "--allow-empty", "-m", "main")
+	runGit(t, "checkout", "-q", "feature")
+	runGit(t, "merge", "-q", "--no-edit", "main")
+
+	// feature has its 2 commits and the merge, main has nothing new
+	ahead, behind, err := CountAheadBehind(wd, "feature", "main")
+	if err != nil || ahead != 3 || behind != 0 {
+		t.Errorf("CountAheadBehind() = %d, %d, %v, want 3, 0", ahead, behind, err)
+	}
+	ahead, behind, err = CountAheadBehind(wd, "main", "feature")
+	if err != nil || ahead != 0 || behind != 3 {
+		t.Errorf("CountAheadBehind() = %d, %d, %v, want 0, 3", ahead, behind, err)
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"conflict", "a\n<<<<<<< HEAD\nb\n=======\nc\n>>>>>>> feature\n", true},
+		{"setext heading", "Title\n=======\n\nText\n", false},
+		{"resolved", "a\nb\nc\n", false},
+		{"unpaired marker", ">>>>>>> quoted in an email\n", false},
+	}
+	wd := t.TempDir()
+	for _, tt := range tests {
+		writeFiles(t, wd, map[string]string{"file.md": tt.content})
+		got, err := HasConflictMarkers(wd, "file.md")
+		if err != nil || got != tt.want {
+			t.Errorf("HasConflictMarkers() %s = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+	if got, err := HasConflictMarkers(wd, "deleted.md"); err != nil || got {
+		t.Errorf("HasConflictMarkers() of a deleted file = %v, %v, want false", got, err)
+	}
+}
@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Fetch the remote and update the remote-tracking branches
+//
+// If prune is true, the remote-tracking branches deleted on the remote are removed
+func Fetch(ctx context.Context, path string, remote string, prune bool, username string, password string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remote,
+		Prune:      prune,
+		Auth: &http.BasicAuth{
+			Username: username,
+			Password: password,
+		},
+		Progress: sidebandProgress(ctx),
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// List the remote-tracking branches of a remote (refs/remotes/<remote>/*)
+//
+// Return a map of the branch name on the remote to the hash of its last commit
+func ListRemoteBranches(path string, remote string) (map[string]string, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	prefix := "refs/remotes/" + remote + "/"
+	branches := map[string]string{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		// HEAD is a symbolic ref to the default branch of the remote, not a branch
+		if ref.Type() != plumbing.HashReference || !strings.HasPrefix(name, prefix) || name == prefix+"HEAD" {
+			return nil
+		}
+		branches[strings.TrimPrefix(name, prefix)] = ref.Hash().String()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+// Fetch a single ref of the remote (e.g. refs/pull/12/head) into localRef, replacing it
+//
+// Return the hash of the commit fetched
+func FetchRef(ctx context.Context, path string, remote string, ref string, localRef string, username string, password string) (string, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return "", err
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + ref + ":" + localRef)},
+		Auth: &http.BasicAuth{
+			Username: username,
+			Password: password,
+		},
+		Progress: sidebandProgress(ctx),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return "", err
+	}
+	fetched, err := repo.Reference(plumbing.ReferenceName(localRef), true)
+	if err != nil {
+		return "", err
+	}
+	return fetched.Hash().String(), nil
+}
@@ -80,7 +80,9 @@ func IsGitInstalled() bool {
 }
 
 // Execute git pull using the exec package
-func GitPull(username string, password string, remote string) error {
+//
+// branch is the name of the branch on the remote
+func GitPull(username string, password string, remote string, branch string) error {
 	// Parse the URL
 	parsedURL, err := url.Parse(remote)
 	if err != nil {
@@ -90,7 +92,7 @@ func GitPull(username string, password string, remote string) error {
 	parsedURL.User = url.UserPassword(username, password)
 
 	// Execute the command
-	err = runCommand("git", "pull", parsedURL.String())
+	err = runCommand("git", "pull", parsedURL.String(), branch)
 	return err
 }
 
@@ -116,7 +118,9 @@ func GitConfigPullRebaseTrueOnlyIfNotSet() error {
 }
 
 // Execute git pull --rebase using the exec package
-func GitPullRebase(username string, password string, remote string) error {
+//
+// branch is the name of the branch on the remote
+func GitPullRebase(username string, password string, remote string, branch string) error {
 	// Parse the URL
 	parsedURL, err := url.Parse(remote)
 	if err != nil {
@@ -126,7 +130,7 @@ func GitPullRebase(username string, password string, remote string) error {
 	parsedURL.User = url.UserPassword(username, password)
 
 	// Execute the command
-	err = runCommand("git", "pull", "--rebase", parsedURL.String())
+	err = runCommand("git", "pull", "--rebase", parsedURL.String(), branch)
 	return err
 
 }
@@ -167,6 +171,11 @@ func GitResetAllHead() error {
 	return runCommand("git", "reset", "--hard", "HEAD")
 }
 
+// Move the current branch to the commit and discard the uncommitted changes
+func GitResetHard(commit string) error {
+	return runCommand("git", "reset", "--hard", "--quiet", commit)
+}
+
 // I prefer to use checkout because git reset gave me some problems
 // When used with a file in args, git reset was thinking it was a directory.
 // Because of that, git reset was just exiting with an error
@@ -257,3 +266,17 @@ func GitStash() error {
 func GitStashPop() error {
 	return runCommand("git", "stash", "pop")
 }
+
+// Point a ref to a commit, creating it if needed. The message is written in the reflog
+func GitUpdateRef(ref string, hash string, message string) error {
+	return runCommand("git", "update-ref", "-m", message, ref, hash)
+}
+
+// Delete a ref. It's not an error if it doesn't exist
+func GitDeleteRef(ref string) error {
+	_, err := runCommandWithOutput("git", "show-ref", "--verify", "--quiet", ref)
+	if err != nil {
+		return nil
+	}
+	return runCommand("git", "update-ref", "-d", ref)
+}
@@ -0,0 +1,242 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// What gut can restore of a repository: the refs, HEAD and the index
+type RepoState struct {
+	// Branch checked out (e.g. refs/heads/main). Empty if HEAD is detached
+	Head string `json:"head"`
+	// Commit HEAD points to. Empty if the repository has no commit
+	HeadHash string `json:"head_hash"`
+	// Branches, tags, stash and the changes gut keeps when switching branches, with their commit
+	Refs map[string]string `json:"refs"`
+	// Tree of the index. Empty if it can't be written (e.g. files with conflicts)
+	Index string `json:"index"`
+	// Tree of the files of the working tree, ignored files excepted
+	Worktree string `json:"worktree"`
+}
+
+// An operation recorded in the journal, with the state of the repository before and after it
+type JournalEntry struct {
+	When time.Time `json:"when"`
+	// Command run (e.g. gut switch feature)
+	Command string    `json:"command"`
+	Before  RepoState `json:"before"`
+	After   RepoState `json:"after"`
+}
+
+// Maximum number of operations kept in the journal
+const maxJournalEntries = 100
+
+// Refs recorded in the journal. Remote branches aren't: they reflect the remote, not what gut did
+var journalRefs = []string{"refs/heads", "refs/tags", "refs/stash", "refs/gut/autostash"}
+
+func journalFile(path string) string {
+	return filepath.Join(path, ".git", "gut", "journal")
+}
+
+// Read the current state of the refs, HEAD and the index
+func GetRepoState(path string) (RepoState, error) {
+	state := RepoState{Refs: map[string]string{}}
+	output, err := runCommandWithOutput(append([]string{"git", "for-each-ref", "--format=%(refname) %(objectname)"}, journalRefs...)...)
+	if err != nil {
+		return state, err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		ref, hash, found := strings.Cut(line, " ")
+		if found {
+			state.Refs[ref] = hash
+		}
+	}
+	// Both fail in a repository without commits, or with a detached HEAD for symbolic-ref
+	head, err := runCommandQuiet("git", "symbolic-ref", "--quiet", "HEAD")
+	if err == nil {
+		state.Head = head
+	}
+	state.HeadHash, _ = runCommandQuiet("git", "rev-parse", "--verify", "--quiet", "HEAD")
+	// write-tree stores the index as a tree, so that it can be read back later
+	state.Index, _ = runCommandQuiet("git", "write-tree")
+	state.Worktree, _ = writeWorktreeTree(path)
+	return state, nil
+}
+
+// Store the files of the working tree as a tree, without changing the index
+func writeWorktreeTree(path string) (string, error) {
+	tmp, err := os.CreateTemp("", "gut-index-")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	// Start from the index so that git doesn't hash the files that haven't changed
+	content, err := os.ReadFile(filepath.Join(path, ".git", "index"))
+	if err == nil {
+		err = os.WriteFile(tmp.Name(), content, 0644)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// git refuses an empty index file, but creates a missing one
+		os.Remove(tmp.Name())
+	}
+	env := "GIT_INDEX_FILE=" + tmp.Name()
+	_, err = runCommandQuiet(env, "git", "add", "--all")
+	if err != nil {
+		return "", err
+	}
+	return runCommandQuiet(env, "git", "write-tree")
+}
+
+// Run a git command and return its trimmed output, without printing its errors
+//
+// Arguments before "git" are environment variables (e.g. GIT_INDEX_FILE=...)
+func runCommandQuiet(arg ...string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	var env []string
+	for len(arg) > 0 && arg[0] != "git" {
+		env = append(env, arg[0])
+		arg = arg[1:]
+	}
+	cmd := exec.Command(arg[0], arg[1:]...)
+	cmd.Dir = wd
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// Ref of the stash. It isn't restored: the other entries of the stash are in its reflog,
+// which moving or deleting the ref would lose
+const StashRef = "refs/stash"
+
+// Put the refs, HEAD, the index and the working tree back as they were in the state
+//
+// The uncommitted changes made since are lost. The stash is left as it is (see StashRef)
+func RestoreRepoState(path string, state RepoState) error {
+	current, err := GetRepoState(path)
+	if err != nil {
+		return err
+	}
+	for ref := range current.Refs {
+		if ref == StashRef {
+			continue
+		}
+		if _, ok := state.Refs[ref]; !ok {
+			err = runCommand("git", "update-ref", "-d", ref)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for ref, hash := range state.Refs {
+		if ref != StashRef && current.Refs[ref] != hash {
+			err = runCommand("git", "update-ref", "-m", "gut undo", ref, hash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if state.Head != "" {
+		err = runCommand("git", "symbolic-ref", "HEAD", state.Head)
+	} else if state.HeadHash != "" {
+		err = runCommand("git", "update-ref", "--no-deref", "-m", "gut undo", "HEAD", state.HeadHash)
+	}
+	if err != nil {
+		return err
+	}
+	if state.HeadHash == "" {
+		return nil
+	}
+	// Make the working tree match HEAD, then bring back what was staged and the other changes
+	err = runCommand("git", "reset", "--hard", "--quiet", "HEAD")
+	if err != nil {
+		return err
+	}
+	if state.Worktree != "" {
+		err = checkoutTree(state.Worktree)
+		if err != nil {
+			return err
+		}
+	}
+	if state.Index != "" {
+		_, err = runCommandQuiet("git", "read-tree", state.Index)
+	}
+	return err
+}
+
+// Write the files of a tree in the working tree, without changing the index
+func checkoutTree(tree string) error {
+	tmp, err := os.CreateTemp("", "gut-index-")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+	env := "GIT_INDEX_FILE=" + tmp.Name()
+	_, err = runCommandQuiet(env, "git", "read-tree", tree)
+	if err != nil {
+		return err
+	}
+	_, err = runCommandQuiet(env, "git", "checkout-index", "--all", "--force")
+	return err
+}
+
+// Add an operation to the journal. The oldest ones are removed past maxJournalEntries
+func AppendJournal(path string, entry JournalEntry) error {
+	entries, err := ReadJournal(path)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry)
+	if len(entries) > maxJournalEntries {
+		entries = entries[len(entries)-maxJournalEntries:]
+	}
+	err = os.MkdirAll(filepath.Dir(journalFile(path)), 0755)
+	if err != nil {
+		return err
+	}
+	var content []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		content = append(append(content, line...), '\n')
+	}
+	return os.WriteFile(journalFile(path), content, 0644)
+}
+
+// List the operations of the journal, from the oldest to the newest
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(journalFile(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	// The refs of big repositories make long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Return true if a merge is in progress (e.g. stopped because of conflicts)
+func IsMerging(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git", "MERGE_HEAD"))
+	return err == nil
+}
+
+// List the files that would have conflicts if the branch was merged into HEAD, without touching the working tree
+//
+// It requires git 2.38 or later. On older versions, an error is returned
+func GitMergePreview(branch string) ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// The output is the tree of the merge, then the files with conflicts, then an empty line and messages
+	cmd := exec.Command("git", "merge-tree", "--write-tree", "--name-only", "HEAD", branch)
+	cmd.Dir = wd
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, err
+	}
+	var conflicts []string
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+		conflicts = append(conflicts, line)
+	}
+	return conflicts, nil
+}
+
+// Move the current branch to the branch if it contains all its commits
+func GitMergeFastForward(branch string) error {
+	return runCommand("git", "merge", "--ff-only", "--quiet", branch)
+}
+
+// Merge the branch into the current branch with a merge commit
+//
+// If there are conflicts, an error is returned and the merge stays in progress
+func GitMerge(branch string, message string) error {
+	return runCommand("git", "merge", "--no-ff", "--quiet", "--message", message, branch)
+}
+
+// Create the merge commit once the conflicts are resolved
+func GitMergeContinue() error {
+	// The list of conflicts git adds as comments is removed
+	return runCommand("git", "commit", "--no-edit", "--cleanup=strip", "--quiet")
+}
+
+// Stop the merge in progress and restore the working tree of HEAD
+func GitMergeAbort() error {
+	return runCommand("git", "merge", "--abort")
+}
+
+// Mark a file as resolved
+func GitAddFile(file string) error {
+	return runCommand("git", "add", "--all", "--", file)
+}
+
+// Resolve the conflicts of a file by taking the version of HEAD (ours) or of the branch merged (theirs)
+func GitResolveWith(file string, ours bool) error {
+	side := "--theirs"
+	if ours {
+		side = "--ours"
+	}
+	err := runCommand("git", "checkout", side, "--", file)
+	if err != nil {
+		return err
+	}
+	return GitAddFile(file)
+}
+
+// Return true if the file still contains a conflict, between a <<<<<<< line and a >>>>>>> line
+//
+// A ======= line alone isn't a conflict: Markdown and reStructuredText use it to underline titles
+func HasConflictMarkers(path string, file string) (bool, error) {
+	f, err := os.Open(filepath.Join(path, file))
+	if os.IsNotExist(err) {
+		// Deleted files can't have markers
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// Some lines might be longer than the default limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	opened := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "<<<<<<< ") {
+			opened = true
+		} else if opened && strings.HasPrefix(line, ">>>>>>> ") {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
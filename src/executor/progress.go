@@ -0,0 +1,226 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+/*
+go-git only gives us the human readable messages sent by the server (the sideband),
+e.g. "Counting objects: 45% (45/100)". We parse them to know the current stage.
+
+The bytes transferred are counted by an HTTP client installed for http and https.
+It finds the TransferProgress to update in the context of the request.
+*/
+
+// Statistics of a network operation (clone, fetch, pull, push) while it's running
+type TransferStats struct {
+	// Stage reported by the server (e.g. "Counting objects") or by gut ("Resolving objects")
+	Stage   string
+	Current int
+	Total   int
+	Percent int
+	// Number of objects counted and compressed by the server
+	ObjectsCounted    int
+	ObjectsCompressed int
+	// Number of objects in the pack sent by the server (from the "Total" line)
+	ObjectsTotal int
+	// Bytes received (fetch) and sent (push) over HTTP
+	BytesReceived int64
+	BytesSent     int64
+	// True once the whole pack has been downloaded and go-git is resolving the objects
+	Resolving bool
+	Elapsed   time.Duration
+}
+
+// Return the number of bytes transferred per second
+func (s TransferStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.BytesReceived+s.BytesSent) / s.Elapsed.Seconds()
+}
+
+// Progress of a network operation, updated by go-git and the HTTP client
+//
+// It implements io.Writer to be used as the Progress option of go-git
+type TransferProgress struct {
+	mu      sync.Mutex
+	stats   TransferStats
+	start   time.Time
+	pending string
+}
+
+func NewTransferProgress() *TransferProgress {
+	return &TransferProgress{start: time.Now()}
+}
+
+// Return a copy of the current statistics
+func (p *TransferProgress) Stats() TransferStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Elapsed = time.Since(p.start)
+	return stats
+}
+
+var (
+	regexStagePercent = regexp.MustCompile(`^([A-Za-z ]+):\s+(\d+)% \((\d+)/(\d+)\)`)
+	regexStageCount   = regexp.MustCompile(`^([A-Za-z ]+):\s+(\d+)`)
+	regexTotal        = regexp.MustCompile(`^Total (\d+)`)
+)
+
+// Receive the sideband messages of the server
+//
+// Messages are terminated by \r when they update the same line, or \n
+func (p *TransferProgress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending += string(b)
+	for {
+		i := strings.IndexAny(p.pending, "\r\n")
+		if i == -1 {
+			break
+		}
+		p.parseLine(strings.TrimSpace(p.pending[:i]))
+		p.pending = p.pending[i+1:]
+	}
+	return len(b), nil
+}
+
+func (p *TransferProgress) parseLine(line string) {
+	line = strings.TrimPrefix(line, "remote: ")
+	if match := regexTotal.FindStringSubmatch(line); match != nil {
+		p.stats.ObjectsTotal, _ = strconv.Atoi(match[1])
+		return
+	}
+	var stage string
+	var current, total, percent int
+	if match := regexStagePercent.FindStringSubmatch(line); match != nil {
+		stage = match[1]
+		percent, _ = strconv.Atoi(match[2])
+		current, _ = strconv.Atoi(match[3])
+		total, _ = strconv.Atoi(match[4])
+	} else if match := regexStageCount.FindStringSubmatch(line); match != nil {
+		// e.g. "Enumerating objects: 5, done."
+		stage = match[1]
+		current, _ = strconv.Atoi(match[2])
+		total = current
+		percent = 100
+	} else {
+		return
+	}
+	p.stats.Stage = stage
+	p.stats.Current = current
+	p.stats.Total = total
+	p.stats.Percent = percent
+	switch stage {
+	case "Counting objects", "Enumerating objects":
+		p.stats.ObjectsCounted = current
+	case "Compressing objects":
+		p.stats.ObjectsCompressed = current
+	}
+}
+
+func (p *TransferProgress) addBytes(received int64, sent int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.BytesReceived += received
+	p.stats.BytesSent += sent
+}
+
+func (p *TransferProgress) setResolving() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Resolving = true
+	p.stats.Stage = "Resolving objects"
+}
+
+type progressContextKey struct{}
+
+// Return a context carrying the progress to update during a network operation
+func WithTransferProgress(ctx context.Context, progress *TransferProgress) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, progress)
+}
+
+// Return the progress carried by the context, or nil
+//
+// The nil value is a valid go-git Progress option: the server won't send any message
+func getTransferProgress(ctx context.Context) *TransferProgress {
+	progress, _ := ctx.Value(progressContextKey{}).(*TransferProgress)
+	return progress
+}
+
+// go-git expects a nil interface (not a nil pointer) to disable the sideband
+func sidebandProgress(ctx context.Context) io.Writer {
+	progress := getTransferProgress(ctx)
+	if progress == nil {
+		return nil
+	}
+	return progress
+}
+
+// Reader counting the bytes read into a TransferProgress
+type countingReader struct {
+	io.ReadCloser
+	progress *TransferProgress
+	sent     bool
+	// True for the response of git-upload-pack, which contains the pack
+	pack bool
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if r.sent {
+		r.progress.addBytes(0, int64(n))
+	} else {
+		r.progress.addBytes(int64(n), 0)
+		// The pack has been downloaded. go-git now decodes it
+		if r.pack && err == io.EOF {
+			r.progress.setResolving()
+		}
+	}
+	return n, err
+}
+
+// HTTP transport counting the bytes of the requests made with a TransferProgress in their context
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	progress := getTransferProgress(req.Context())
+	if progress == nil {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body = &countingReader{ReadCloser: req.Body, progress: progress, sent: true}
+	}
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = &countingReader{
+		ReadCloser: res.Body,
+		progress:   progress,
+		pack:       req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "git-upload-pack"),
+	}
+	return res, nil
+}
+
+func init() {
+	httpClient := githttp.NewClient(&http.Client{
+		Transport: &countingTransport{base: http.DefaultTransport},
+	})
+	client.InstallProtocol("http", httpClient)
+	client.InstallProtocol("https", httpClient)
+}
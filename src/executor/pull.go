@@ -1,12 +1,34 @@
 package executor
 
 import (
+	"context"
+	"errors"
+
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 
 	"github.com/go-git/go-git/v5"
 )
 
-func Pull(path string, remote string, username string, password string) error {
+// Returned by Pull when the branch has never been pushed to the remote
+var ErrRemoteBranchNotFound = errors.New("the branch doesn't exist on the remote")
+
+// Return the name of the branch on the remote for a local branch
+//
+// If the branch tracks a branch on this remote, we use the name of the tracked branch.
+// Otherwise, we assume the branch has the same name on the remote
+func GetRemoteBranchName(path string, remote string, branch string) string {
+	upstream, err := GetUpstream(path, branch)
+	if err == nil && upstream.Remote == remote && upstream.Branch != "" {
+		return upstream.Branch
+	}
+	return branch
+}
+
+// Pull the upstream of branch from the remote and fast-forward the current branch
+//
+// Return ErrRemoteBranchNotFound if the branch doesn't exist on the remote yet
+func Pull(ctx context.Context, path string, remote string, branch string, username string, password string) error {
 	repo, err := OpenRepo(path)
 	if err != nil {
 		return err
@@ -15,13 +37,18 @@ func Pull(path string, remote string, username string, password string) error {
 	if err != nil {
 		return err
 	}
-	err = worktree.Pull(&git.PullOptions{
-		RemoteName: remote,
+	err = worktree.PullContext(ctx, &git.PullOptions{
+		RemoteName:    remote,
+		ReferenceName: plumbing.NewBranchReferenceName(GetRemoteBranchName(path, remote, branch)),
 		Auth: &http.BasicAuth{
 			Username: username,
 			Password: password,
 		},
+		Progress: sidebandProgress(ctx),
 	})
+	if err == plumbing.ErrReferenceNotFound {
+		return ErrRemoteBranchNotFound
+	}
 	if err != nil {
 		return err
 	}
@@ -1,25 +1,132 @@
 package executor
 
 import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 
 	"github.com/go-git/go-git/v5"
 )
 
-func Push(path string, remote string, username string, password string) error {
+// Push branch to its upstream on the remote
+//
+// If the branch doesn't track anything yet, it's pushed under the same name
+// and the upstream is recorded in the git config (like git push -u)
+func Push(ctx context.Context, path string, remote string, branch string, username string, password string) error {
 	repo, err := OpenRepo(path)
 	if err != nil {
 		return err
 	}
-	err = repo.Push(&git.PushOptions{
+	upstream, err := GetUpstream(path, branch)
+	if err != nil {
+		return err
+	}
+	remoteBranch := GetRemoteBranchName(path, remote, branch)
+	err = repo.PushContext(ctx, &git.PushOptions{
 		RemoteName: remote,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec("refs/heads/" + branch + ":refs/heads/" + remoteBranch),
+		},
 		Auth: &http.BasicAuth{
 			Username: username,
 			Password: password,
 		},
+		Progress: sidebandProgress(ctx),
 	})
+	if err != nil && strings.HasPrefix(err.Error(), "non-fast-forward update") {
+		return git.ErrNonFastForwardUpdate
+	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	// First push of this branch: we record the upstream
+	if upstream.Remote == "" {
+		errUpstream := SetUpstream(path, branch, remote, remoteBranch)
+		if errUpstream != nil {
+			return errUpstream
+		}
+	}
+	return err
+}
+
+// Push branch to a mirror under the same name
+//
+// Unlike Push, the upstream of the branch is left untouched: it should stay on the remote we pull from
+func PushMirror(ctx context.Context, path string, remote string, branch string, username string, password string) error {
+	repo, err := OpenRepo(path)
 	if err != nil {
 		return err
 	}
-	return nil
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: remote,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec("refs/heads/" + branch + ":refs/heads/" + branch),
+		},
+		Auth: &http.BasicAuth{
+			Username: username,
+			Password: password,
+		},
+		Progress: sidebandProgress(ctx),
+	})
+	if err != nil && strings.HasPrefix(err.Error(), "non-fast-forward update") {
+		return git.ErrNonFastForwardUpdate
+	}
+	return err
+}
+
+// Returned by PushForceWithLease when the remote branch has moved since the lease was taken
+var ErrStaleLease = errors.New("the remote branch has been updated since the last fetch")
+
+// Force push branch to its upstream, only if the remote branch is still at lease
+//
+// lease is the hash of the remote branch the last time it was fetched.
+// If someone else pushed in the meantime, nothing is pushed and ErrStaleLease is returned
+func PushForceWithLease(ctx context.Context, path string, remote string, branch string, lease string, username string, password string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	remoteRef := "refs/heads/" + GetRemoteBranchName(path, remote, branch)
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: remote,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec("+refs/heads/" + branch + ":" + remoteRef),
+		},
+		// The check is done by go-git against the refs advertised by the remote
+		// in the same connection as the push
+		RequireRemoteRefs: []config.RefSpec{
+			config.RefSpec(lease + ":" + remoteRef),
+		},
+		Auth: &http.BasicAuth{
+			Username: username,
+			Password: password,
+		},
+		Progress: sidebandProgress(ctx),
+	})
+	if err != nil && strings.HasPrefix(err.Error(), "remote ref "+remoteRef+" required to be") {
+		return ErrStaleLease
+	}
+	return err
+}
+
+// Delete a branch on the remote
+func DeleteRemoteBranch(ctx context.Context, path string, remote string, remoteBranch string, username string, password string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	return repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: remote,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(":refs/heads/" + remoteBranch),
+		},
+		Auth: &http.BasicAuth{
+			Username: username,
+			Password: password,
+		},
+		Progress: sidebandProgress(ctx),
+	})
 }
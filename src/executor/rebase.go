@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// State of an interactive rebase stopped in the middle (conflicts, or a commit to edit)
+type RebaseState struct {
+	// Branch being rewritten (e.g. main)
+	Branch string
+	// Tip of the branch before the rebase
+	OrigHead string
+	// Commit the rebase stopped at. Empty if it didn't stop on a commit
+	Stopped string
+	// True if the rebase stopped to let the user edit the commit
+	Edit bool
+	// Number of steps done and total number of steps
+	Done  int
+	Total int
+}
+
+func rebaseDir(path string) string {
+	return filepath.Join(path, ".git", "rebase-merge")
+}
+
+// Return true if a rebase is in progress
+func IsRebasing(path string) bool {
+	if _, err := os.Stat(rebaseDir(path)); err == nil {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(path, ".git", "rebase-apply"))
+	return err == nil
+}
+
+// Read the state of the interactive rebase in progress
+func GetRebaseState(path string) (RebaseState, error) {
+	dir := rebaseDir(path)
+	read := func(name string) string {
+		content, _ := os.ReadFile(filepath.Join(dir, name))
+		return strings.TrimSpace(string(content))
+	}
+	headName := read("head-name")
+	if headName == "" {
+		return RebaseState{}, os.ErrNotExist
+	}
+	state := RebaseState{
+		Branch:   strings.TrimPrefix(headName, "refs/heads/"),
+		OrigHead: read("orig-head"),
+		Stopped:  read("stopped-sha"),
+	}
+	// The amend file is written when the rebase stops at an edit command
+	if _, err := os.Stat(filepath.Join(dir, "amend")); err == nil {
+		state.Edit = true
+	}
+	state.Done, _ = strconv.Atoi(read("msgnum"))
+	state.Total, _ = strconv.Atoi(read("end"))
+	return state, nil
+}
+
+// Quote a string for the shell git uses to run the editor and the exec commands
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Run git rebase with the environment variables
+//
+// The hints of git are silenced as gut tells the user what to do. They're returned in the error instead
+func runRebase(env []string, arg ...string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("git", arg...)
+	cmd.Dir = wd
+	var stderr bytes.Buffer
+	cmd.Stdout = &stderr
+	cmd.Stderr = &stderr
+	// Commits squashed together keep both messages, without opening an editor
+	cmd.Env = append(os.Environ(), append([]string{"GIT_EDITOR=true"}, env...)...)
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Rewrite the commits after base by running git rebase -i with the todo list given
+//
+// The todo list uses the syntax of git (pick, drop, squash, edit, exec). If the rebase stops
+// (conflicts or edit), an error is returned and the rebase stays in progress
+func GitRebaseInteractive(path string, base string, todo string) error {
+	todoFile := filepath.Join(path, ".git", "gut", "rewrite-todo")
+	err := os.MkdirAll(filepath.Dir(todoFile), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(todoFile, []byte(todo), 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(todoFile)
+	// git calls the editor with the path of its todo list, which we replace with ours
+	editor := "cp " + ShellQuote(filepath.ToSlash(todoFile))
+	return runRebase([]string{"GIT_SEQUENCE_EDITOR=" + editor}, "rebase", "--interactive", "--quiet", base)
+}
+
+// Continue the rebase once the conflicts are resolved or the commit is edited
+//
+// If the rebase stopped at an edit command, the staged changes are added to the commit
+func GitRebaseContinue() error {
+	return runRebase(nil, "rebase", "--continue")
+}
+
+// Skip the commit the rebase stopped at
+func GitRebaseSkip() error {
+	return runRebase(nil, "rebase", "--skip")
+}
+
+// Stop the rebase and restore the branch as it was before it
+func GitRebaseAbort() error {
+	return runCommand("git", "rebase", "--abort")
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// A line of a reflog written by git
+type ReflogEntry struct {
+	Old     string
+	New     string
+	When    time.Time
+	Message string
+}
+
+// A switch from a branch to another
+type Checkout struct {
+	From string
+	To   string
+	When time.Time
+}
+
+// File where gut records the branches checked out, because go-git doesn't write the reflog of HEAD
+func checkoutsFile(path string) string {
+	return filepath.Join(path, ".git", "gut", "checkouts")
+}
+
+// Read the reflog of a reference (e.g. HEAD or refs/stash), from the oldest entry to the newest
+//
+// Return an empty list if the reference has no reflog
+func ReadReflog(path string, ref string) ([]ReflogEntry, error) {
+	f, err := os.Open(filepath.Join(path, ".git", "logs", filepath.FromSlash(ref)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ReflogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// <old> <new> <name> <<email>> <timestamp> <timezone>\t<message>
+		line, message, _ := strings.Cut(scanner.Text(), "\t")
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, ReflogEntry{
+			Old:     fields[0],
+			New:     fields[1],
+			When:    time.Unix(timestamp, 0),
+			Message: message,
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// Record that gut switched from a branch to another
+//
+// from is empty if HEAD was detached
+func RecordCheckout(path string, from string, to string) error {
+	err := os.MkdirAll(filepath.Dir(checkoutsFile(path)), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(checkoutsFile(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%d\t%s\t%s\n", time.Now().Unix(), from, to)
+	return err
+}
+
+// List the checkouts recorded by gut and the ones made with git, from the oldest to the newest
+func ListCheckouts(path string) ([]Checkout, error) {
+	var checkouts []Checkout
+	content, err := os.ReadFile(checkoutsFile(path))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		checkouts = append(checkouts, Checkout{From: fields[1], To: fields[2], When: time.Unix(timestamp, 0)})
+	}
+
+	reflog, err := ReadReflog(path, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range reflog {
+		// checkout: moving from main to feature
+		moving, found := strings.CutPrefix(entry.Message, "checkout: moving from ")
+		if !found {
+			continue
+		}
+		from, to, found := strings.Cut(moving, " to ")
+		if !found {
+			continue
+		}
+		checkouts = append(checkouts, Checkout{From: from, To: to, When: entry.When})
+	}
+
+	sort.SliceStable(checkouts, func(i, j int) bool {
+		return checkouts[i].When.Before(checkouts[j].When)
+	})
+	return checkouts, nil
+}
+
+// List the local branches from the most recently checked out to the least
+//
+// Branches that have never been checked out, or that have been deleted, are not listed
+func ListRecentBranches(path string) ([]string, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	checkouts, err := ListCheckouts(path)
+	if err != nil {
+		return nil, err
+	}
+	// The branch we leave was used just before the branch we switch to
+	lastUse := map[string]int{}
+	for i, checkout := range checkouts {
+		lastUse[checkout.From] = 2 * i
+		lastUse[checkout.To] = 2*i + 1
+	}
+	var branches []string
+	for branch := range lastUse {
+		if branch == "" {
+			continue
+		}
+		if _, err := repo.Reference(plumbing.NewBranchReferenceName(branch), false); err != nil {
+			continue
+		}
+		branches = append(branches, branch)
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return lastUse[branches[i]] > lastUse[branches[j]]
+	})
+	return branches, nil
+}
+
+// Count the stash entries created on each branch
+func CountStashesByBranch(path string) (map[string]int, error) {
+	entries, err := ReadReflog(path, "refs/stash")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, entry := range entries {
+		if branch, _ := parseStashSubject(entry.Message); branch != "" {
+			counts[branch]++
+		}
+	}
+	return counts, nil
+}
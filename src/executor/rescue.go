@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// List the commits that no branch, tag or other ref leads to anymore
+//
+// Commits only kept by a reflog are listed too. Only the last commit of each lost chain is returned
+func ListDanglingCommits() ([]string, error) {
+	output, err := runCommandQuiet("git", "fsck", "--no-reflogs", "--no-progress")
+	if err != nil && output == "" {
+		return nil, err
+	}
+	var hashes []string
+	for _, line := range strings.Split(output, "\n") {
+		// dangling commit <hash>
+		if hash, found := strings.CutPrefix(line, "dangling commit "); found {
+			hashes = append(hashes, strings.TrimSpace(hash))
+		}
+	}
+	return hashes, nil
+}
+
+// Read the reflogs of HEAD and of the local branches, by ref
+func ReadAllReflogs(path string) (map[string][]ReflogEntry, error) {
+	reflogs := map[string][]ReflogEntry{}
+	head, err := ReadReflog(path, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	reflogs["HEAD"] = head
+	logs := filepath.Join(path, ".git", "logs")
+	err = filepath.WalkDir(filepath.Join(logs, "refs", "heads"), func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ref, err := filepath.Rel(logs, file)
+		if err != nil {
+			return err
+		}
+		ref = filepath.ToSlash(ref)
+		reflogs[ref], err = ReadReflog(path, ref)
+		return err
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return reflogs, nil
+}
+
+// Get a commit from its full hash, even if no branch leads to it
+func GetCommit(path string, hash string) (object.Commit, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return object.Commit{}, err
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return object.Commit{}, err
+	}
+	return *commit, nil
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Namespace of the refs where gut keeps the changes it discards
+const snapshotPrefix = "refs/gut/snapshots/"
+
+// Changes of the working tree saved by gut before discarding them
+type Snapshot struct {
+	// Ref of the snapshot (e.g. refs/gut/snapshots/20240102-150405)
+	Ref  string
+	Hash string
+	When time.Time
+}
+
+// Save the files of the working tree, untracked files included, in a commit kept in refs/gut/snapshots/<date>
+//
+// The commit has HEAD as parent and message as message. Return an empty ref if there is nothing to save
+func CreateSnapshot(path string, message string) (string, error) {
+	tree, err := writeWorktreeTree(path)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"git", "commit-tree", tree, "-m", message}
+	head, err := runCommandQuiet("git", "rev-parse", "--verify", "--quiet", "HEAD")
+	if err == nil {
+		headTree, _ := runCommandQuiet("git", "rev-parse", "HEAD^{tree}")
+		if headTree == tree {
+			return "", nil
+		}
+		args = append(args, "-p", head)
+	}
+	// The snapshot mustn't fail because the user hasn't set their name, so gut signs it
+	hash, err := runCommandQuiet(append([]string{
+		"GIT_AUTHOR_NAME=gut", "GIT_AUTHOR_EMAIL=gut@localhost",
+		"GIT_COMMITTER_NAME=gut", "GIT_COMMITTER_EMAIL=gut@localhost",
+	}, args...)...)
+	if err != nil {
+		return "", err
+	}
+
+	name := snapshotPrefix + time.Now().Format("20060102-150405")
+	ref := name
+	for i := 2; ; i++ {
+		// An empty old value makes update-ref fail if the ref already exists
+		_, err = runCommandQuiet("git", "update-ref", "-m", message, ref, hash, "")
+		if err == nil {
+			return ref, nil
+		}
+		if i > 10 {
+			return "", err
+		}
+		ref = name + "-" + strconv.Itoa(i)
+	}
+}
+
+// Return the command that brings back the changes saved in a snapshot
+//
+// Only the changes since the parent of the snapshot are applied (deletions included), so it works after HEAD has moved.
+// If files are given, only these files are restored as they were in the snapshot
+func SnapshotRestoreCommand(ref string, files ...string) string {
+	if len(files) == 0 {
+		return "git cherry-pick --no-commit " + ref
+	}
+	command := "git checkout " + ref + " --"
+	for _, file := range files {
+		if strings.ContainsAny(file, " '\"$`\\*?&;|<>()") {
+			file = ShellQuote(file)
+		}
+		command += " " + file
+	}
+	return command
+}
+
+// List the snapshots, from the oldest to the newest
+func ListSnapshots() ([]Snapshot, error) {
+	output, err := runCommandQuiet("git", "for-each-ref", "--sort=committerdate", "--format=%(refname) %(objectname) %(committerdate:unix)", snapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Ref: fields[0], Hash: fields[1], When: time.Unix(timestamp, 0)})
+	}
+	return snapshots, nil
+}
+
+// Delete the snapshots made before a date. Return the number of snapshots deleted
+func PruneSnapshots(before time.Time) (int, error) {
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, snapshot := range snapshots {
+		if !snapshot.When.Before(before) {
+			continue
+		}
+		err = GitDeleteRef(snapshot.Ref)
+		if err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Move the current branch to the commit but keep the changes in the working tree, unstaged
+func GitResetMixed(commit string) error {
+	return runCommand("git", "reset", "--quiet", commit)
+}
+
+// Record the new files in the index without their content, so that they show up in
+// git diff and can be added hunk by hunk
+//
+// Only untracked files are recorded: deleted files must stay unstaged
+func GitAddIntentToAdd() error {
+	files, err := listFiles("git", "ls-files", "--others", "--exclude-standard")
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	return runCommand(append([]string{"git", "add", "--intent-to-add", "--"}, files...)...)
+}
+
+// List the files with changes that aren't staged yet
+func GitListUnstagedFiles() ([]string, error) {
+	return listFiles("git", "diff", "--name-only")
+}
+
+// Run a command listing files, one per line
+func listFiles(arg ...string) ([]string, error) {
+	output, err := runCommandWithOutput(arg...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(output, "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Let the user choose which hunks to stage with git add --patch
+func GitAddPatch() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	// git reads the answers of the user directly from the terminal
+	cmd := exec.Command("git", "add", "--patch")
+	cmd.Dir = wd
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Return true if some changes are staged
+func GitHasStagedChanges() (bool, error) {
+	err := runCommand("git", "diff", "--cached", "--quiet")
+	if err == nil {
+		return false, nil
+	}
+	// git diff --quiet exits with 1 when there are changes
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+// Commit the staged changes with the message
+func GitCommit(message string) error {
+	return runCommand("git", "commit", "--quiet", "-m", message)
+}
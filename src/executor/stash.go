@@ -0,0 +1,229 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// An entry of the stash
+type StashEntry struct {
+	// Position in the stash, 0 being the last entry
+	Index int
+	Hash  string
+	// Branch the changes were stashed on. Empty if unknown
+	Branch  string
+	Message string
+	When    time.Time
+}
+
+// Name of the entry for the git cli (e.g. stash@{0})
+func (s StashEntry) Name() string {
+	return fmt.Sprintf("stash@{%d}", s.Index)
+}
+
+// Split the subject of a stash entry (e.g. On main: message) into the branch and the message
+func parseStashSubject(subject string) (string, string) {
+	// WIP on main: 1a2b3c4 Title of the commit, or On main: message of the stash
+	trimmed := strings.TrimPrefix(subject, "WIP ")
+	branch, found := strings.CutPrefix(trimmed, "on ")
+	if !found {
+		branch, found = strings.CutPrefix(trimmed, "On ")
+	}
+	if !found {
+		return "", subject
+	}
+	branch, message, found := strings.Cut(branch, ": ")
+	if !found {
+		return "", subject
+	}
+	return branch, message
+}
+
+// List the entries of the stash, from the last one to the first one
+func ListStashes(path string) ([]StashEntry, error) {
+	reflog, err := ReadReflog(path, "refs/stash")
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StashEntry, len(reflog))
+	for i, entry := range reflog {
+		index := len(reflog) - 1 - i
+		branch, message := parseStashSubject(entry.Message)
+		entries[index] = StashEntry{
+			Index:   index,
+			Hash:    entry.New,
+			Branch:  branch,
+			Message: message,
+			When:    entry.When,
+		}
+	}
+	return entries, nil
+}
+
+// List the files changed by a stash entry, including the untracked ones, using the git cli
+func GitStashFiles(entry StashEntry) ([]string, error) {
+	output, err := runCommandWithOutput("git", "stash", "show", "--name-only", "--include-untracked", entry.Name())
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(output, "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Print the diff of a stash entry using the git cli
+func GitStashShow(entry StashEntry) error {
+	return runCommandWithStdin("git", "stash", "show", "--patch", "--include-untracked", entry.Name())
+}
+
+// Apply a stash entry and keep it in the stash
+func GitStashApply(entry StashEntry) error {
+	return runCommand("git", "stash", "apply", "--quiet", entry.Name())
+}
+
+// Apply a stash entry and remove it from the stash. If it conflicts, it's kept
+func GitStashPopEntry(entry StashEntry) error {
+	return runCommand("git", "stash", "pop", "--quiet", entry.Name())
+}
+
+// Remove a stash entry
+func GitStashDrop(entry StashEntry) error {
+	return runCommand("git", "stash", "drop", "--quiet", entry.Name())
+}
+
+// Remove all the entries of the stash
+func GitStashClear() error {
+	return runCommand("git", "stash", "clear")
+}
+
+// Create a branch from the commit the entry was stashed on, check it out, and apply the entry
+//
+// The entry is removed from the stash if it applies without conflict
+func GitStashBranch(entry StashEntry, branch string) error {
+	return runCommand("git", "stash", "branch", branch, entry.Name())
+}
+
+// Changes left on a branch by gut switch, restored when the user comes back to it
+type Autostash struct {
+	Branch string
+	Hash   string
+	When   time.Time
+}
+
+// Reference where the changes left on a branch are saved
+func autostashRef(branch string) string {
+	return "refs/gut/autostash/" + branch
+}
+
+// Return the hash of the last stash entry. Empty if the stash is empty
+func gitStashHead() string {
+	output, err := runCommandWithOutput("git", "rev-parse", "--quiet", "--verify", "refs/stash")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// Stash the changes using the git cli
+//
+// Return false if there was nothing to stash
+func GitStashPush(message string, includeUntracked bool) (bool, error) {
+	before := gitStashHead()
+	args := []string{"git", "stash", "push", "--quiet", "--message", message}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	err := runCommand(args...)
+	if err != nil {
+		return false, err
+	}
+	return gitStashHead() != before, nil
+}
+
+// Save the changes of the working tree under a reference of the branch and clean the working tree
+//
+// If changes were already saved for the branch, they are moved to the stash so that nothing is lost.
+// Return false if there was nothing to save
+func GitAutostash(branch string) (bool, error) {
+	stashed, err := GitStashPush("gut autostash on "+branch, true)
+	if err != nil || !stashed {
+		return false, err
+	}
+	hash := gitStashHead()
+	previous, previousErr := runCommandWithOutput("git", "rev-parse", "--quiet", "--verify", autostashRef(branch))
+	// Until the reference is set, the changes stay in the stash
+	err = runCommand("git", "update-ref", "-m", "gut autostash", autostashRef(branch), hash)
+	if err != nil {
+		return false, err
+	}
+	err = runCommand("git", "stash", "drop", "--quiet")
+	if err != nil {
+		return false, err
+	}
+	if previousErr == nil {
+		err = runCommand("git", "stash", "store", "--message", "gut autostash on "+branch+" (older)", strings.TrimSpace(previous))
+	}
+	return true, err
+}
+
+// Get the changes saved for the branch by GitAutostash
+func GetAutostash(path string, branch string) (Autostash, bool, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return Autostash{}, false, err
+	}
+	ref, err := repo.Reference(plumbing.ReferenceName(autostashRef(branch)), false)
+	if err == plumbing.ErrReferenceNotFound {
+		return Autostash{}, false, nil
+	}
+	if err != nil {
+		return Autostash{}, false, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return Autostash{}, false, err
+	}
+	return Autostash{Branch: branch, Hash: ref.Hash().String(), When: commit.Author.When}, true, nil
+}
+
+// Apply the changes saved for the branch, then forget them
+//
+// If they conflict with the working tree, they are kept under the reference of the branch
+func GitApplyAutostash(autostash Autostash) error {
+	err := runCommand("git", "stash", "apply", "--quiet", autostash.Hash)
+	if err != nil {
+		return err
+	}
+	return runCommand("git", "update-ref", "-d", autostashRef(autostash.Branch))
+}
+
+// Move the changes saved for the branch to the stash, so that they can be restored with git stash
+func GitMoveAutostashToStash(autostash Autostash) error {
+	err := runCommand("git", "stash", "store", "--message", "gut autostash on "+autostash.Branch, autostash.Hash)
+	if err != nil {
+		return err
+	}
+	return runCommand("git", "update-ref", "-d", autostashRef(autostash.Branch))
+}
+
+// List the files with conflicts using the git cli
+func GitListConflicts() ([]string, error) {
+	output, err := runCommandWithOutput("git", "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(output, "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
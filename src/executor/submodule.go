@@ -0,0 +1,163 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+type Submodule struct {
+	Name string
+	// Path of the submodule relative to the root of the repository
+	Path string
+	URL  string
+	// False if the submodule has never been cloned
+	Initialized bool
+	// Commit recorded in the repository
+	Expected string
+	// Commit checked out in the submodule. Empty if not initialized
+	Current string
+}
+
+// Return true if the commit checked out is the one recorded in the repository
+func (s Submodule) IsUpToDate() bool {
+	return s.Initialized && s.Current == s.Expected
+}
+
+// Return true if the repository has a .gitmodules file
+func HasSubmodules(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".gitmodules"))
+	return err == nil
+}
+
+// List the submodules of the repository with the commit recorded and the commit checked out
+func ListSubmodules(path string) ([]Submodule, error) {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return nil, err
+	}
+	var list []Submodule
+	for _, sub := range submodules {
+		status, err := sub.Status()
+		if err != nil {
+			return nil, err
+		}
+		submodule := Submodule{
+			Name:        sub.Config().Name,
+			Path:        sub.Config().Path,
+			URL:         sub.Config().URL,
+			Initialized: !status.Current.IsZero(),
+			Expected:    status.Expected.String(),
+		}
+		if submodule.Initialized {
+			submodule.Current = status.Current.String()
+		}
+		list = append(list, submodule)
+	}
+	return list, nil
+}
+
+// Return true if the submodule has no uncommitted changes
+//
+// Untracked files are ignored. A submodule that is not initialized is considered clean
+func IsSubmoduleClean(path string, submodule Submodule) (bool, error) {
+	if !submodule.Initialized {
+		return true, nil
+	}
+	// IsWorkTreeClean stages the files of the working directory, which would be the parent repository here
+	repo, err := OpenRepo(filepath.Join(path, submodule.Path))
+	if err != nil {
+		return false, err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+	// Untracked files are not lost when another commit is checked out
+	for _, statusFile := range status {
+		if statusFile.Worktree == git.Untracked {
+			continue
+		}
+		if statusFile.Staging != git.Unmodified || statusFile.Worktree != git.Unmodified {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Check out the commit recorded for the submodule. Clone it first if needed
+//
+// The missing commits are fetched with the credentials if the URL uses HTTP.
+// Nested submodules are updated with the same credentials
+func UpdateSubmodule(ctx context.Context, path string, name string, username string, password string) error {
+	repo, err := OpenRepo(path)
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	sub, err := worktree.Submodule(name)
+	if err != nil {
+		return err
+	}
+	var auth transport.AuthMethod
+	if username != "" && isHTTPURL(sub.Config().URL) {
+		auth = &http.BasicAuth{
+			Username: username,
+			Password: password,
+		}
+	}
+	return sub.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              auth,
+	})
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// Register the repository cloned in subPath as a submodule using the git cli
+//
+// subPath must already contain the clone of url, so that git doesn't need to download it again
+func GitSubmoduleAdd(url string, subPath string) error {
+	err := runCommand("git", "submodule", "add", "--quiet", url, subPath)
+	if err != nil {
+		return err
+	}
+	// Move the .git folder of the clone into .git/modules like git submodule add does
+	return runCommand("git", "submodule", "absorbgitdirs", "--", subPath)
+}
+
+// Unregister a submodule, remove its files and its git folder using the git cli
+func GitSubmoduleRemove(path string, submodule Submodule) error {
+	err := runCommand("git", "submodule", "deinit", "--force", "--", submodule.Path)
+	if err != nil {
+		return err
+	}
+	err = runCommand("git", "rm", "--force", "--quiet", "--", submodule.Path)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(path, ".git", "modules", submodule.Name))
+}
@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Remotes used by gut sync when the repository is mirrored on several remotes
+//
+// Stored in the [sync] section of the .gut file
+type SyncConf struct {
+	// Name of the remote to pull from
+	Pull string `toml:"pull,omitempty"`
+	// Profile used to pull. If empty, the profile of the push target with the same remote is used
+	PullProfileID string `toml:"pull_profile_id,omitempty"`
+	// Remotes to push to
+	Push []SyncTarget `toml:"push,omitempty"`
+}
+
+// A remote gut sync pushes to, with the profile used to authenticate
+type SyncTarget struct {
+	Remote    string `toml:"remote"`
+	ProfileID string `toml:"profile_id,omitempty"`
+}
+
+// Naming convention of the new branches and the branch they start from
+//
+// Stored in the [branch] section of the .gut file
+type BranchConf struct {
+	// Template of the branch names with the placeholders {type}, {ticket} and {slug} (e.g. {type}/{ticket}-{slug})
+	Convention string `toml:"convention,omitempty"`
+	// Values allowed for {type}. If empty, any lowercase word is allowed
+	Types []string `toml:"types,omitempty"`
+	// Branch new branches start from: "default" for the default branch, "current" (or empty) for the current branch
+	Base string `toml:"base,omitempty"`
+}
+
+// How long gut keeps the snapshots of the changes it discards
+//
+// Stored in the [snapshot] section of the .gut file
+type SnapshotConf struct {
+	// Number of days the snapshots are kept. If 0, they're kept 14 days. If negative, they're never pruned
+	RetentionDays int `toml:"retention_days,omitempty"`
+}
+
+// Read the .gut file of the path
+//
+// Return an empty SchemaGutConf if the file doesn't exist
+func GetGutConf(path string) (SchemaGutConf, error) {
+	conf := SchemaGutConf{}
+	_, err := toml.DecodeFile(filepath.Join(path, ".gut"), &conf)
+	if os.IsNotExist(err) {
+		return SchemaGutConf{}, nil
+	}
+	return conf, err
+}
+
+// Write the .gut file of the path
+//
+// The whole file is replaced. To update a field, read it first with GetGutConf
+func SaveGutConf(path string, conf SchemaGutConf) error {
+	conf.UpdatedAt = time.Now().Format("2006-01-02 15:04:05")
+	f, err := os.OpenFile(filepath.Join(path, ".gut"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(conf)
+}
+
+// Rule protecting the branches matching Pattern from some operations
+//
+// Stored as [[protect]] tables in the .gut file
+type ProtectRule struct {
+	// Branch name or glob (e.g. main, release/*)
+	Pattern string `toml:"pattern"`
+	// Forbid gut save on the branch: changes must be saved on another branch
+	NoSave bool `toml:"no_save,omitempty"`
+	// Forbid rewriting the history of the branch (squash, fix, overwrite the remote branch)
+	NoRewrite bool `toml:"no_rewrite,omitempty"`
+	// Forbid deleting or renaming the branch
+	NoDelete bool `toml:"no_delete,omitempty"`
+	// Forbid pushing to the branch: changes must go through pull requests
+	PullRequestOnly bool `toml:"pull_request_only,omitempty"`
+}
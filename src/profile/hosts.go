@@ -0,0 +1,81 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// A self-hosted website (e.g. GitHub Enterprise, a GitLab instance) and the API gut uses on it
+//
+// Stored as [[host]] tables in ~/.gut/hosts.toml
+type HostConf struct {
+	// Host of the remote URLs (e.g. git.company.com)
+	Host string `toml:"host"`
+	// Type of the website: github, gitlab, bitbucket or gitea (forgejo is an alias of gitea)
+	Provider string `toml:"provider"`
+	// Base URL of the API. If empty, the default of the provider is used (e.g. https://git.company.com/api/v4)
+	API string `toml:"api,omitempty"`
+}
+
+type hostsFile struct {
+	Host []HostConf `toml:"host"`
+}
+
+func getHostsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gut", "hosts.toml"), nil
+}
+
+// Read the hosts config
+//
+// Return an empty slice if the file doesn't exist
+func GetHosts() ([]HostConf, error) {
+	path, err := getHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	var file hostsFile
+	_, err = toml.DecodeFile(path, &file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return file.Host, err
+}
+
+// Find the config of a host. The second value is false if the host isn't configured
+func GetHost(host string) (HostConf, bool, error) {
+	hosts, err := GetHosts()
+	if err != nil {
+		return HostConf{}, false, err
+	}
+	for _, conf := range hosts {
+		if strings.EqualFold(conf.Host, host) {
+			return conf, true, nil
+		}
+	}
+	return HostConf{}, false, nil
+}
+
+// Write the hosts config. The whole file is replaced
+func SaveHosts(hosts []HostConf) error {
+	path, err := getHostsPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(hostsFile{Host: hosts})
+}
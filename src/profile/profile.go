@@ -488,6 +488,18 @@ func GetProfileFromPath(path string) (Profile, error) {
 	return Profile{}, errors.New("no profile found globally")
 }
 
+// Return the profile with the given id
+func GetProfileByID(id string) (Profile, error) {
+	// Load profile data in global variable
+	loadProfileData()
+	for _, profile := range profiles {
+		if profile.Id == id {
+			return profile, nil
+		}
+	}
+	return Profile{}, errors.New("no profile found with this id")
+}
+
 func IsAliasAlreadyUsed(alias string) bool {
 	// Load profile data in global variable
 	loadProfileData()
@@ -502,6 +514,10 @@ func IsAliasAlreadyUsed(alias string) bool {
 }
 
 type SchemaGutConf struct {
-	ProfileID string `toml:"profile_id"`
-	UpdatedAt string `toml:"updated_at"`
+	ProfileID string        `toml:"profile_id"`
+	UpdatedAt string        `toml:"updated_at"`
+	Sync      SyncConf      `toml:"sync,omitempty"`
+	Protect   []ProtectRule `toml:"protect,omitempty"`
+	Branch    BranchConf    `toml:"branch,omitempty"`
+	Snapshot  SnapshotConf  `toml:"snapshot,omitempty"`
 }
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error returned when an API answers with an error status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("the API answered with the status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("the API answered with the status %d: %s", e.StatusCode, e.Message)
+}
+
+// Find the message of an error response
+//
+// GitHub and GitLab use {"message": ...}, Bitbucket uses {"error": {"message": ...}}
+func parseErrorMessage(content []byte) string {
+	var decoded struct {
+		Message json.RawMessage `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(content, &decoded) != nil {
+		return strings.TrimSpace(string(content))
+	}
+	if decoded.Error.Message != "" {
+		return decoded.Error.Message
+	}
+	var message string
+	if json.Unmarshal(decoded.Message, &message) == nil {
+		return message
+	}
+	// GitLab returns the validation errors as an object or an array
+	return string(decoded.Message)
+}
+
+// Send a request to an API with a JSON body and decode the JSON response into out
+//
+// body and out can be nil. setAuth adds the credentials to the request
+func requestJSON(method string, url string, setAuth func(*http.Request), body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	setAuth(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Message: parseErrorMessage(content)}
+	}
+	if out == nil || len(content) == 0 {
+		return nil
+	}
+	return json.Unmarshal(content, out)
+}
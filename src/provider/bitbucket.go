@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Bitbucket Cloud
+type Bitbucket struct {
+	// Base URL of the API (e.g. https://api.bitbucket.org/2.0)
+	API string
+	// Base URL of the website (e.g. https://bitbucket.org)
+	Web string
+}
+
+func (b *Bitbucket) Name() string {
+	return "Bitbucket"
+}
+
+func (b *Bitbucket) PullRequestName() string {
+	return "pull request"
+}
+
+// Bitbucket uses the username and the app password of the profile
+func (b *Bitbucket) Auth(credentials Credentials) func(*http.Request) {
+	return func(req *http.Request) {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+}
+
+func (b *Bitbucket) CompareURL(repo string, base string, head string) string {
+	return b.Web + "/" + repo + "/pull-requests/new?source=" + url.QueryEscape(head) + "&dest=" + url.QueryEscape(base)
+}
+
+func (b *Bitbucket) DiffURL(repo string, from string, to string) string {
+	// The refs are separated by a carriage return, the newest first
+	return b.Web + "/" + repo + "/branches/compare/" + url.PathEscape(to+"\r"+from) + "#diff"
+}
+
+// Find the uuids of the members of a Bitbucket workspace from their nicknames
+func (b *Bitbucket) userUUIDs(credentials Credentials, workspace string, nicknames []string) ([]string, error) {
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
+	var members struct {
+		Values []struct {
+			User struct {
+				UUID      string `json:"uuid"`
+				Nickname  string `json:"nickname"`
+				AccountID string `json:"account_id"`
+			} `json:"user"`
+		} `json:"values"`
+	}
+	err := requestJSON("GET", b.API+"/workspaces/"+url.PathEscape(workspace)+"/members?pagelen=100", b.Auth(credentials), nil, &members)
+	if err != nil {
+		return nil, err
+	}
+	var uuids []string
+	for _, nickname := range nicknames {
+		found := false
+		for _, member := range members.Values {
+			if member.User.Nickname == nickname || member.User.AccountID == nickname {
+				uuids = append(uuids, member.User.UUID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s isn't a member of the workspace %s", nickname, workspace)
+		}
+	}
+	return uuids, nil
+}
+
+// Open a pull request on Bitbucket
+//
+// Bitbucket doesn't have labels, so an error is returned if some are requested
+func (b *Bitbucket) CreatePullRequest(credentials Credentials, options PullRequestOptions) (PullRequest, error) {
+	if len(options.Labels) > 0 {
+		return PullRequest{}, errors.New("Bitbucket doesn't support labels")
+	}
+	workspace, _, _ := strings.Cut(options.Repo, "/")
+	uuids, err := b.userUUIDs(credentials, workspace, options.Reviewers)
+	if err != nil {
+		return PullRequest{}, fmt.Errorf("I can't find the reviewers: %w", err)
+	}
+	reviewers := []map[string]string{}
+	for _, uuid := range uuids {
+		reviewers = append(reviewers, map[string]string{"uuid": uuid})
+	}
+	var created struct {
+		ID    int `json:"id"`
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	err = requestJSON("POST", b.API+"/repositories/"+options.Repo+"/pullrequests", b.Auth(credentials), map[string]interface{}{
+		"title":       options.Title,
+		"description": options.Body,
+		"source":      map[string]interface{}{"branch": map[string]string{"name": options.Head}},
+		"destination": map[string]interface{}{"branch": map[string]string{"name": options.Base}},
+		"reviewers":   reviewers,
+		"draft":       options.Draft,
+	}, &created)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	return PullRequest{Number: created.ID, URL: created.Links.HTML.Href}, nil
+}
+
+func (b *Bitbucket) GetRepository(credentials Credentials, repo string) (Repository, error) {
+	var decoded struct {
+		FullName    string `json:"full_name"`
+		Description string `json:"description"`
+		IsPrivate   bool   `json:"is_private"`
+		MainBranch  struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	err := requestJSON("GET", b.API+"/repositories/"+repo, b.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{
+		FullName:      decoded.FullName,
+		Description:   decoded.Description,
+		DefaultBranch: decoded.MainBranch.Name,
+		Private:       decoded.IsPrivate,
+		URL:           decoded.Links.HTML.Href,
+	}, nil
+}
+
+// Bitbucket doesn't return the email of the user
+func (b *Bitbucket) GetUser(credentials Credentials) (User, error) {
+	var decoded struct {
+		Username    string `json:"username"`
+		DisplayName string `json:"display_name"`
+	}
+	err := requestJSON("GET", b.API+"/user", b.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return User{}, err
+	}
+	return User{Username: decoded.Username, Name: decoded.DisplayName}, nil
+}
+
+// A pull request as returned by the API of Bitbucket
+type bitbucketPullRequest struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Draft  bool   `json:"draft"`
+	Author struct {
+		Nickname string `json:"nickname"`
+	} `json:"author"`
+	Source      bitbucketEndpoint `json:"source"`
+	Destination bitbucketEndpoint `json:"destination"`
+	Links       struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	// Only returned for a single pull request
+	Participants []struct {
+		User struct {
+			Nickname string `json:"nickname"`
+		} `json:"user"`
+		// approved, changes_requested or null
+		State string `json:"state"`
+	} `json:"participants"`
+}
+
+type bitbucketEndpoint struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (p bitbucketPullRequest) toPullRequest() PullRequest {
+	return PullRequest{
+		Number: p.ID,
+		URL:    p.Links.HTML.Href,
+		Title:  p.Title,
+		Author: p.Author.Nickname,
+		Head:   p.Source.Branch.Name,
+		Base:   p.Destination.Branch.Name,
+		Draft:  p.Draft,
+		Fork:   p.Source.Repository.FullName != p.Destination.Repository.FullName,
+	}
+}
+
+func (b *Bitbucket) ListPullRequests(credentials Credentials, repo string) ([]PullRequest, error) {
+	var decoded struct {
+		Values []bitbucketPullRequest `json:"values"`
+	}
+	err := requestJSON("GET", b.API+"/repositories/"+repo+"/pullrequests?state=OPEN&pagelen=50", b.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	prs := make([]PullRequest, len(decoded.Values))
+	for i, p := range decoded.Values {
+		prs[i] = p.toPullRequest()
+	}
+	return prs, nil
+}
+
+// Bitbucket doesn't tell if a pull request can be merged, so Mergeable stays empty
+func (b *Bitbucket) GetPullRequest(credentials Credentials, repo string, number int) (PullRequest, error) {
+	var decoded bitbucketPullRequest
+	err := requestJSON("GET", fmt.Sprintf("%s/repositories/%s/pullrequests/%d", b.API, repo, number), b.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	pr := decoded.toPullRequest()
+	var states []review
+	for _, participant := range decoded.Participants {
+		state := ""
+		switch participant.State {
+		case "approved":
+			state = "approved"
+		case "changes_requested":
+			state = "changes"
+		}
+		states = append(states, review{User: participant.User.Nickname, State: state})
+	}
+	pr.Review, pr.ApprovedBy = reviewDecision(states)
+	return pr, nil
+}
+
+// Bitbucket doesn't expose the pull requests as refs: the source branch must be fetched instead
+func (b *Bitbucket) PullRequestRef(number int) string {
+	return ""
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Gitea and Forgejo (e.g. Codeberg)
+type Gitea struct {
+	// Base URL of the API (e.g. https://codeberg.org/api/v1)
+	API string
+	// Base URL of the website (e.g. https://codeberg.org)
+	Web string
+}
+
+func (g *Gitea) Name() string {
+	return "Gitea"
+}
+
+func (g *Gitea) PullRequestName() string {
+	return "pull request"
+}
+
+// Gitea only uses the token of the profile
+func (g *Gitea) Auth(credentials Credentials) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+credentials.Password)
+	}
+}
+
+func (g *Gitea) CompareURL(repo string, base string, head string) string {
+	return g.Web + "/" + repo + "/compare/" + base + "..." + head
+}
+
+func (g *Gitea) DiffURL(repo string, from string, to string) string {
+	return g.Web + "/" + repo + "/compare/" + from + "..." + to
+}
+
+// Find the ids of the labels of a repository from their names
+func (g *Gitea) labelIDs(credentials Credentials, repo string, names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var labels []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	err := requestJSON("GET", g.API+"/repos/"+repo+"/labels?limit=100", g.Auth(credentials), nil, &labels)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, name := range names {
+		found := false
+		for _, label := range labels {
+			if label.Name == name {
+				ids = append(ids, label.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("the label %s doesn't exist in %s", name, repo)
+		}
+	}
+	return ids, nil
+}
+
+// Open a pull request on Gitea, then request the reviews
+//
+// Gitea doesn't have drafts: the title is prefixed with WIP: instead
+func (g *Gitea) CreatePullRequest(credentials Credentials, options PullRequestOptions) (PullRequest, error) {
+	// Labels are set by id, so we look them up before creating the pull request
+	labelIDs, err := g.labelIDs(credentials, options.Repo, options.Labels)
+	if err != nil {
+		return PullRequest{}, fmt.Errorf("I can't find the labels: %w", err)
+	}
+	title := options.Title
+	if options.Draft {
+		title = "WIP: " + title
+	}
+	body := map[string]interface{}{
+		"title": title,
+		"body":  options.Body,
+		"head":  options.Head,
+		"base":  options.Base,
+	}
+	if len(labelIDs) > 0 {
+		body["labels"] = labelIDs
+	}
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	err = requestJSON("POST", g.API+"/repos/"+options.Repo+"/pulls", g.Auth(credentials), body, &created)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	pr := PullRequest{Number: created.Number, URL: created.HTMLURL}
+
+	if len(options.Reviewers) > 0 {
+		err = requestJSON("POST", fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", g.API, options.Repo, pr.Number), g.Auth(credentials), map[string]interface{}{
+			"reviewers": options.Reviewers,
+		}, nil)
+		if err != nil {
+			return pr, &PartialError{fmt.Errorf("I can't request the reviews: %w", err)}
+		}
+	}
+	return pr, nil
+}
+
+func (g *Gitea) GetRepository(credentials Credentials, repo string) (Repository, error) {
+	var decoded struct {
+		FullName      string `json:"full_name"`
+		Description   string `json:"description"`
+		DefaultBranch string `json:"default_branch"`
+		Private       bool   `json:"private"`
+		HTMLURL       string `json:"html_url"`
+	}
+	err := requestJSON("GET", g.API+"/repos/"+repo, g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{
+		FullName:      decoded.FullName,
+		Description:   decoded.Description,
+		DefaultBranch: decoded.DefaultBranch,
+		Private:       decoded.Private,
+		URL:           decoded.HTMLURL,
+	}, nil
+}
+
+func (g *Gitea) GetUser(credentials Credentials) (User, error) {
+	var decoded struct {
+		Login    string `json:"login"`
+		FullName string `json:"full_name"`
+		Email    string `json:"email"`
+	}
+	err := requestJSON("GET", g.API+"/user", g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return User{}, err
+	}
+	return User{Username: decoded.Login, Name: decoded.FullName, Email: decoded.Email}, nil
+}
+
+// Gitea marks drafts with a prefix in the title
+func isGiteaDraft(title string) bool {
+	upper := strings.ToUpper(title)
+	return strings.HasPrefix(upper, "WIP:") || strings.HasPrefix(upper, "[WIP]") || strings.HasPrefix(upper, "DRAFT:")
+}
+
+func (g *Gitea) ListPullRequests(credentials Credentials, repo string) ([]PullRequest, error) {
+	// The pull requests have the same shape as on GitHub
+	var decoded []githubPullRequest
+	err := requestJSON("GET", g.API+"/repos/"+repo+"/pulls?state=open&limit=50", g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	prs := make([]PullRequest, len(decoded))
+	for i, p := range decoded {
+		prs[i] = p.toPullRequest()
+		prs[i].Draft = prs[i].Draft || isGiteaDraft(p.Title)
+	}
+	return prs, nil
+}
+
+func (g *Gitea) GetPullRequest(credentials Credentials, repo string, number int) (PullRequest, error) {
+	auth := g.Auth(credentials)
+	var decoded githubPullRequest
+	err := requestJSON("GET", fmt.Sprintf("%s/repos/%s/pulls/%d", g.API, repo, number), auth, nil, &decoded)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	pr := decoded.toPullRequest()
+	pr.Draft = pr.Draft || isGiteaDraft(decoded.Title)
+	if decoded.Mergeable != nil {
+		if *decoded.Mergeable {
+			pr.Mergeable = MergeReady
+		} else {
+			pr.Mergeable = MergeConflicts
+		}
+	}
+
+	var reviews []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		State     string `json:"state"`
+		Dismissed bool   `json:"dismissed"`
+	}
+	err = requestJSON("GET", fmt.Sprintf("%s/repos/%s/pulls/%d/reviews", g.API, repo, number), auth, nil, &reviews)
+	if err != nil {
+		return pr, err
+	}
+	var states []review
+	for _, r := range reviews {
+		state := githubReviewState(r.State)
+		if r.Dismissed {
+			state = "dismissed"
+		}
+		states = append(states, review{User: r.User.Login, State: state})
+	}
+	pr.Review, pr.ApprovedBy = reviewDecision(states)
+	return pr, nil
+}
+
+func (g *Gitea) PullRequestRef(number int) string {
+	return fmt.Sprintf("refs/pull/%d/head", number)
+}
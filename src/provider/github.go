@@ -238,3 +238,230 @@ func GitHub_GetUserName(token string) (string, error) {
 	return user.Login, nil
 
 }
+
+/* -------------------------------- Provider -------------------------------- */
+
+// GitHub and GitHub Enterprise Server
+type GitHub struct {
+	// Base URL of the API (e.g. https://api.github.com)
+	API string
+	// Base URL of the website (e.g. https://github.com)
+	Web string
+}
+
+func (g *GitHub) Name() string {
+	return "GitHub"
+}
+
+func (g *GitHub) PullRequestName() string {
+	return "pull request"
+}
+
+// GitHub only uses the token of the profile
+func (g *GitHub) Auth(credentials Credentials) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+credentials.Password)
+		req.Header.Set("X-Github-Api-Version", "2022-11-28")
+	}
+}
+
+// https://docs.github.com/en/pull-requests/collaborating-with-pull-requests/proposing-changes-to-your-work-with-pull-requests/creating-a-pull-request
+func (g *GitHub) CompareURL(repo string, base string, head string) string {
+	return g.Web + "/" + repo + "/compare/" + base + "..." + head + "?quick_pull=1"
+}
+
+// https://docs.github.com/en/pull-requests/committing-changes-to-your-project/viewing-and-comparing-commits/comparing-commits
+func (g *GitHub) DiffURL(repo string, from string, to string) string {
+	return g.Web + "/" + repo + "/compare/" + from + ".." + to
+}
+
+// Open a pull request on GitHub, then request the reviews and add the labels
+func (g *GitHub) CreatePullRequest(credentials Credentials, options PullRequestOptions) (PullRequest, error) {
+	auth := g.Auth(credentials)
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	err := requestJSON("POST", g.API+"/repos/"+options.Repo+"/pulls", auth, map[string]interface{}{
+		"title": options.Title,
+		"body":  options.Body,
+		"head":  options.Head,
+		"base":  options.Base,
+		"draft": options.Draft,
+	}, &created)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	pr := PullRequest{Number: created.Number, URL: created.HTMLURL}
+
+	if len(options.Reviewers) > 0 {
+		err = requestJSON("POST", fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", g.API, options.Repo, pr.Number), auth, map[string]interface{}{
+			"reviewers": options.Reviewers,
+		}, nil)
+		if err != nil {
+			return pr, &PartialError{fmt.Errorf("I can't request the reviews: %w", err)}
+		}
+	}
+	if len(options.Labels) > 0 {
+		// On GitHub, pull requests are issues
+		err = requestJSON("POST", fmt.Sprintf("%s/repos/%s/issues/%d/labels", g.API, options.Repo, pr.Number), auth, map[string]interface{}{
+			"labels": options.Labels,
+		}, nil)
+		if err != nil {
+			return pr, &PartialError{fmt.Errorf("I can't add the labels: %w", err)}
+		}
+	}
+	return pr, nil
+}
+
+func (g *GitHub) GetRepository(credentials Credentials, repo string) (Repository, error) {
+	var decoded struct {
+		FullName      string `json:"full_name"`
+		Description   string `json:"description"`
+		DefaultBranch string `json:"default_branch"`
+		Private       bool   `json:"private"`
+		HTMLURL       string `json:"html_url"`
+	}
+	err := requestJSON("GET", g.API+"/repos/"+repo, g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{
+		FullName:      decoded.FullName,
+		Description:   decoded.Description,
+		DefaultBranch: decoded.DefaultBranch,
+		Private:       decoded.Private,
+		URL:           decoded.HTMLURL,
+	}, nil
+}
+
+func (g *GitHub) GetUser(credentials Credentials) (User, error) {
+	var decoded struct {
+		Login string `json:"login"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	err := requestJSON("GET", g.API+"/user", g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return User{}, err
+	}
+	return User{Username: decoded.Login, Name: decoded.Name, Email: decoded.Email}, nil
+}
+
+// A pull request as returned by the API of GitHub and Gitea
+type githubPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Draft   bool   `json:"draft"`
+	User    struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		Ref  string `json:"ref"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Ref  string `json:"ref"`
+		Repo struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"base"`
+	// Only returned for a single pull request
+	Mergeable      *bool  `json:"mergeable"`
+	MergeableState string `json:"mergeable_state"`
+}
+
+func (p githubPullRequest) toPullRequest() PullRequest {
+	return PullRequest{
+		Number: p.Number,
+		URL:    p.HTMLURL,
+		Title:  p.Title,
+		Author: p.User.Login,
+		Head:   p.Head.Ref,
+		Base:   p.Base.Ref,
+		Draft:  p.Draft,
+		// The repository of the head is null if the fork is deleted
+		Fork: p.Head.Repo == nil || p.Head.Repo.FullName != p.Base.Repo.FullName,
+	}
+}
+
+func (g *GitHub) ListPullRequests(credentials Credentials, repo string) ([]PullRequest, error) {
+	var decoded []githubPullRequest
+	err := requestJSON("GET", g.API+"/repos/"+repo+"/pulls?state=open&per_page=100", g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	prs := make([]PullRequest, len(decoded))
+	for i, p := range decoded {
+		prs[i] = p.toPullRequest()
+	}
+	return prs, nil
+}
+
+func (g *GitHub) GetPullRequest(credentials Credentials, repo string, number int) (PullRequest, error) {
+	auth := g.Auth(credentials)
+	var decoded githubPullRequest
+	err := requestJSON("GET", fmt.Sprintf("%s/repos/%s/pulls/%d", g.API, repo, number), auth, nil, &decoded)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	pr := decoded.toPullRequest()
+
+	// https://docs.github.com/en/graphql/reference/enums#mergestatestatus
+	switch decoded.MergeableState {
+	case "clean", "has_hooks":
+		pr.Mergeable = MergeReady
+	case "dirty":
+		pr.Mergeable = MergeConflicts
+	case "behind":
+		pr.Mergeable = MergeBehind
+	case "unstable":
+		pr.Mergeable = MergeChecksFailing
+	case "blocked", "draft":
+		pr.Mergeable = MergeBlocked
+	case "unknown":
+		pr.Mergeable = MergeChecking
+	}
+	if decoded.Mergeable == nil && pr.Mergeable == "" {
+		// GitHub computes it in the background after a push
+		pr.Mergeable = MergeChecking
+	}
+
+	var reviews []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		State string `json:"state"`
+	}
+	err = requestJSON("GET", fmt.Sprintf("%s/repos/%s/pulls/%d/reviews?per_page=100", g.API, repo, number), auth, nil, &reviews)
+	if err != nil {
+		return pr, err
+	}
+	var states []review
+	for _, r := range reviews {
+		states = append(states, review{User: r.User.Login, State: githubReviewState(r.State)})
+	}
+	pr.Review, pr.ApprovedBy = reviewDecision(states)
+	return pr, nil
+}
+
+// Convert the state of a review of GitHub or Gitea
+func githubReviewState(state string) string {
+	switch state {
+	case "APPROVED":
+		return "approved"
+	case "CHANGES_REQUESTED", "REQUEST_CHANGES":
+		return "changes"
+	case "DISMISSED":
+		return "dismissed"
+	}
+	return ""
+}
+
+func (g *GitHub) PullRequestRef(number int) string {
+	return fmt.Sprintf("refs/pull/%d/head", number)
+}
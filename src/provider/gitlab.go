@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLab and its self-hosted instances
+type GitLab struct {
+	// Base URL of the API (e.g. https://gitlab.com/api/v4)
+	API string
+	// Base URL of the website (e.g. https://gitlab.com)
+	Web string
+}
+
+func (g *GitLab) Name() string {
+	return "GitLab"
+}
+
+func (g *GitLab) PullRequestName() string {
+	return "merge request"
+}
+
+// GitLab only uses the token of the profile
+func (g *GitLab) Auth(credentials Credentials) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+credentials.Password)
+	}
+}
+
+func (g *GitLab) CompareURL(repo string, base string, head string) string {
+	return g.Web + "/" + repo + "/-/merge_requests/new?merge_request%5Bsource_branch%5D=" + url.QueryEscape(head) + "&merge_request%5Btarget_branch%5D=" + url.QueryEscape(base)
+}
+
+// https://stackoverflow.com/a/50070145/15573415
+func (g *GitLab) DiffURL(repo string, from string, to string) string {
+	return g.Web + "/" + repo + "/-/compare?from=" + url.QueryEscape(from) + "&to=" + url.QueryEscape(to)
+}
+
+// Find the ids of GitLab users from their usernames
+func (g *GitLab) userIDs(credentials Credentials, usernames []string) ([]int, error) {
+	var ids []int
+	for _, username := range usernames {
+		var users []struct {
+			ID int `json:"id"`
+		}
+		err := requestJSON("GET", g.API+"/users?username="+url.QueryEscape(username), g.Auth(credentials), nil, &users)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("the user %s doesn't exist", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+// Open a merge request on GitLab
+func (g *GitLab) CreatePullRequest(credentials Credentials, options PullRequestOptions) (PullRequest, error) {
+	// Reviewers are set by id, so we look them up before creating the merge request
+	reviewerIDs, err := g.userIDs(credentials, options.Reviewers)
+	if err != nil {
+		return PullRequest{}, fmt.Errorf("I can't find the reviewers: %w", err)
+	}
+	title := options.Title
+	if options.Draft {
+		title = "Draft: " + title
+	}
+	body := map[string]interface{}{
+		"source_branch": options.Head,
+		"target_branch": options.Base,
+		"title":         title,
+		"description":   options.Body,
+	}
+	if len(reviewerIDs) > 0 {
+		body["reviewer_ids"] = reviewerIDs
+	}
+	if len(options.Labels) > 0 {
+		body["labels"] = strings.Join(options.Labels, ",")
+	}
+	var created struct {
+		IID    int    `json:"iid"`
+		WebURL string `json:"web_url"`
+	}
+	err = requestJSON("POST", g.API+"/projects/"+url.PathEscape(options.Repo)+"/merge_requests", g.Auth(credentials), body, &created)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	return PullRequest{Number: created.IID, URL: created.WebURL}, nil
+}
+
+func (g *GitLab) GetRepository(credentials Credentials, repo string) (Repository, error) {
+	var decoded struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		Description       string `json:"description"`
+		DefaultBranch     string `json:"default_branch"`
+		Visibility        string `json:"visibility"`
+		WebURL            string `json:"web_url"`
+	}
+	err := requestJSON("GET", g.API+"/projects/"+url.PathEscape(repo), g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{
+		FullName:      decoded.PathWithNamespace,
+		Description:   decoded.Description,
+		DefaultBranch: decoded.DefaultBranch,
+		Private:       decoded.Visibility != "public",
+		URL:           decoded.WebURL,
+	}, nil
+}
+
+func (g *GitLab) GetUser(credentials Credentials) (User, error) {
+	var decoded struct {
+		Username string `json:"username"`
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+	}
+	err := requestJSON("GET", g.API+"/user", g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return User{}, err
+	}
+	return User{Username: decoded.Username, Name: decoded.Name, Email: decoded.Email}, nil
+}
+
+// A merge request as returned by the API of GitLab
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+	Title  string `json:"title"`
+	Draft  bool   `json:"draft"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	SourceBranch        string `json:"source_branch"`
+	TargetBranch        string `json:"target_branch"`
+	SourceProjectID     int    `json:"source_project_id"`
+	TargetProjectID     int    `json:"target_project_id"`
+	DetailedMergeStatus string `json:"detailed_merge_status"`
+}
+
+func (m gitlabMergeRequest) toPullRequest() PullRequest {
+	return PullRequest{
+		Number: m.IID,
+		URL:    m.WebURL,
+		Title:  m.Title,
+		Author: m.Author.Username,
+		Head:   m.SourceBranch,
+		Base:   m.TargetBranch,
+		Draft:  m.Draft,
+		Fork:   m.SourceProjectID != m.TargetProjectID,
+	}
+}
+
+func (g *GitLab) ListPullRequests(credentials Credentials, repo string) ([]PullRequest, error) {
+	var decoded []gitlabMergeRequest
+	err := requestJSON("GET", g.API+"/projects/"+url.PathEscape(repo)+"/merge_requests?state=opened&per_page=100", g.Auth(credentials), nil, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	prs := make([]PullRequest, len(decoded))
+	for i, m := range decoded {
+		prs[i] = m.toPullRequest()
+	}
+	return prs, nil
+}
+
+func (g *GitLab) GetPullRequest(credentials Credentials, repo string, number int) (PullRequest, error) {
+	auth := g.Auth(credentials)
+	project := g.API + "/projects/" + url.PathEscape(repo)
+	var decoded gitlabMergeRequest
+	err := requestJSON("GET", fmt.Sprintf("%s/merge_requests/%d", project, number), auth, nil, &decoded)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	pr := decoded.toPullRequest()
+
+	// https://docs.gitlab.com/ee/api/merge_requests.html#merge-status
+	switch decoded.DetailedMergeStatus {
+	case "mergeable":
+		pr.Mergeable = MergeReady
+	case "conflict":
+		pr.Mergeable = MergeConflicts
+	case "need_rebase":
+		pr.Mergeable = MergeBehind
+	case "ci_must_pass":
+		pr.Mergeable = MergeChecksFailing
+	case "checking", "unchecked", "preparing", "approvals_syncing", "ci_still_running":
+		pr.Mergeable = MergeChecking
+	case "":
+		// Instances older than GitLab 15.6 don't have it
+	default:
+		pr.Mergeable = MergeBlocked
+	}
+
+	var approvals struct {
+		ApprovalsLeft int `json:"approvals_left"`
+		ApprovedBy    []struct {
+			User struct {
+				Username string `json:"username"`
+			} `json:"user"`
+		} `json:"approved_by"`
+	}
+	err = requestJSON("GET", fmt.Sprintf("%s/merge_requests/%d/approvals", project, number), auth, nil, &approvals)
+	if err != nil {
+		return pr, err
+	}
+	for _, approval := range approvals.ApprovedBy {
+		pr.ApprovedBy = append(pr.ApprovedBy, approval.User.Username)
+	}
+	switch {
+	case decoded.DetailedMergeStatus == "requested_changes":
+		pr.Review = ReviewChangesRequested
+	case len(pr.ApprovedBy) > 0 && approvals.ApprovalsLeft == 0:
+		pr.Review = ReviewApproved
+	default:
+		pr.Review = ReviewPending
+	}
+	return pr, nil
+}
+
+func (g *GitLab) PullRequestRef(number int) string {
+	return fmt.Sprintf("refs/merge-requests/%d/head", number)
+}
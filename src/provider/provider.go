@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// A website hosting git repositories (GitHub, GitLab, Bitbucket, Gitea...)
+//
+// Repositories are identified by their path on the website (e.g. julien040/gut)
+type Provider interface {
+	// Name of the website, shown to the user (e.g. GitHub)
+	Name() string
+	// How the website calls pull requests (e.g. merge request on GitLab)
+	PullRequestName() string
+	// Add the credentials of a profile to a request to the API
+	Auth(credentials Credentials) func(*http.Request)
+	// URL of the page to open a pull request merging head into base
+	CompareURL(repo string, base string, head string) string
+	// URL of the page showing the changes between two refs. Empty if the website doesn't have one
+	DiffURL(repo string, from string, to string) string
+	// Open a pull request
+	CreatePullRequest(credentials Credentials, options PullRequestOptions) (PullRequest, error)
+	// List the open pull requests of a repository. Review and Mergeable aren't filled
+	ListPullRequests(credentials Credentials, repo string) ([]PullRequest, error)
+	// Get a pull request with its review state and whether it can be merged
+	GetPullRequest(credentials Credentials, repo string, number int) (PullRequest, error)
+	// Ref of the head of a pull request on the remote (e.g. refs/pull/12/head). Empty if the website doesn't have one
+	PullRequestRef(number int) string
+	// Get the information of a repository
+	GetRepository(credentials Credentials, repo string) (Repository, error)
+	// Get the user the credentials belong to
+	GetUser(credentials Credentials) (User, error)
+}
+
+// Credentials of a profile. Password is the token for websites using tokens
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// What a pull request is made of
+type PullRequestOptions struct {
+	// Path of the repository on the website (e.g. julien040/gut)
+	Repo string
+	// Branch with the changes
+	Head string
+	// Branch the changes are merged into
+	Base      string
+	Title     string
+	Body      string
+	Reviewers []string
+	Labels    []string
+	Draft     bool
+}
+
+// A pull request (a merge request on GitLab)
+//
+// CreatePullRequest only fills Number and URL
+type PullRequest struct {
+	Number int
+	URL    string
+	Title  string
+	Author string
+	// Branch with the changes
+	Head string
+	// Branch the changes are merged into
+	Base  string
+	Draft bool
+	// True if Head is a branch of another repository
+	Fork bool
+	// One of the Review constants. Empty if unknown
+	Review string
+	// Users who approved the pull request
+	ApprovedBy []string
+	// One of the Merge constants. Empty if unknown
+	Mergeable string
+}
+
+// Review states of a pull request
+const (
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes requested"
+	ReviewPending          = "waiting for review"
+)
+
+// Whether a pull request can be merged
+const (
+	MergeReady         = "ready to merge"
+	MergeConflicts     = "has conflicts"
+	MergeBehind        = "behind the base branch"
+	MergeChecksFailing = "checks failing"
+	MergeBlocked       = "blocked by the rules of the repository"
+	MergeChecking      = "being checked"
+)
+
+// A review of a pull request. State is approved, changes, or empty for comments
+type review struct {
+	User  string
+	State string
+}
+
+// Find the review state of a pull request from its reviews (oldest first)
+//
+// Only the last review of each user counts, and comments don't change it
+func reviewDecision(reviews []review) (string, []string) {
+	last := map[string]string{}
+	var users []string
+	for _, r := range reviews {
+		if r.State == "" {
+			continue
+		}
+		if _, ok := last[r.User]; !ok {
+			users = append(users, r.User)
+		}
+		last[r.User] = r.State
+	}
+	var approvedBy []string
+	changes := false
+	for _, user := range users {
+		switch last[user] {
+		case "approved":
+			approvedBy = append(approvedBy, user)
+		case "changes":
+			changes = true
+		}
+	}
+	if changes {
+		return ReviewChangesRequested, approvedBy
+	}
+	if len(approvedBy) > 0 {
+		return ReviewApproved, approvedBy
+	}
+	return ReviewPending, nil
+}
+
+// Error returned when the pull request is opened, but the reviewers or the labels couldn't be set
+type PartialError struct {
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return "the pull request is opened, but " + e.Err.Error()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// A repository on the website
+type Repository struct {
+	// Path of the repository (e.g. julien040/gut)
+	FullName      string
+	Description   string
+	DefaultBranch string
+	Private       bool
+	URL           string
+}
+
+// A user of the website
+type User struct {
+	Username string
+	Name     string
+	// Empty if the user keeps it private
+	Email string
+}
+
+// Types of provider, used in the hosts config
+const (
+	KindGitHub    = "github"
+	KindGitLab    = "gitlab"
+	KindBitbucket = "bitbucket"
+	KindGitea     = "gitea"
+)
+
+// Types of provider the user can choose from
+var Kinds = []string{KindGitHub, KindGitLab, KindBitbucket, KindGitea}
+
+// Websites known without any config
+var knownHosts = map[string]string{
+	"github.com":    KindGitHub,
+	"gitlab.com":    KindGitLab,
+	"bitbucket.org": KindBitbucket,
+	"codeberg.org":  KindGitea,
+	"gitea.com":     KindGitea,
+}
+
+// Return the type of provider of a public website, or an empty string if the host isn't known
+func KnownKind(host string) string {
+	return knownHosts[strings.ToLower(host)]
+}
+
+// Return the base URL of the API of a provider on a host
+//
+// Self-hosted instances expose their API under the same host (e.g. https://git.company.com/api/v4 for GitLab)
+func DefaultAPI(kind string, host string) string {
+	switch kind {
+	case KindGitHub:
+		if host == "github.com" {
+			return "https://api.github.com"
+		}
+		// GitHub Enterprise Server
+		return "https://" + host + "/api/v3"
+	case KindGitLab:
+		return "https://" + host + "/api/v4"
+	case KindBitbucket:
+		// Only Bitbucket Cloud is supported: Bitbucket Data Center has another API
+		return "https://api.bitbucket.org/2.0"
+	case KindGitea, "forgejo":
+		return "https://" + host + "/api/v1"
+	}
+	return ""
+}
+
+// Create the provider of a host
+//
+// "forgejo" is accepted as a kind, as Forgejo shares the API of Gitea. If apiURL is empty, DefaultAPI is used
+func New(kind string, host string, apiURL string) (Provider, error) {
+	kind = strings.ToLower(kind)
+	if kind == "forgejo" {
+		kind = KindGitea
+	}
+	if apiURL == "" {
+		apiURL = DefaultAPI(kind, host)
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	webURL := "https://" + host
+	switch kind {
+	case KindGitHub:
+		return &GitHub{API: apiURL, Web: webURL}, nil
+	case KindGitLab:
+		return &GitLab{API: apiURL, Web: webURL}, nil
+	case KindBitbucket:
+		return &Bitbucket{API: apiURL, Web: webURL}, nil
+	case KindGitea:
+		return &Gitea{API: apiURL, Web: webURL}, nil
+	}
+	return nil, fmt.Errorf("%s isn't a known provider. Use one of %s", kind, strings.Join(Kinds, ", "))
+}
@@ -0,0 +1,347 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Start a server answering the requests with the handlers, by method and path
+//
+// The decoded bodies of the requests are stored in received, by method and path
+func newMockServer(t *testing.T, handlers map[string]func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, map[string]map[string]interface{}) {
+	received := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.EscapedPath()
+		handler, ok := handlers[key]
+		if !ok {
+			t.Errorf("unexpected request %s", key)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body map[string]interface{}
+		if json.NewDecoder(r.Body).Decode(&body) == nil {
+			received[key] = body
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func respond(status int, body string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func TestGitHub_CreatePullRequest(t *testing.T) {
+	server, received := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /repos/julien040/gut/pulls": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("Authorization = %q, want the token", r.Header.Get("Authorization"))
+			}
+			respond(201, `{"number": 42, "html_url": "https://github.com/julien040/gut/pull/42"}`)(w, r)
+		},
+		"POST /repos/julien040/gut/pulls/42/requested_reviewers": respond(201, `{}`),
+		"POST /repos/julien040/gut/issues/42/labels":             respond(200, `[]`),
+	})
+
+	pr, err := (&GitHub{API: server.URL}).CreatePullRequest(Credentials{Password: "token"}, PullRequestOptions{
+		Repo:      "julien040/gut",
+		Head:      "feat/login",
+		Base:      "main",
+		Title:     "Add the login page",
+		Body:      "- Add the form",
+		Reviewers: []string{"octocat"},
+		Labels:    []string{"enhancement"},
+		Draft:     true,
+	})
+	if err != nil {
+		t.Fatalf("GitHub.CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 42 || pr.URL != "https://github.com/julien040/gut/pull/42" {
+		t.Errorf("GitHub.CreatePullRequest() = %+v", pr)
+	}
+	created := received["POST /repos/julien040/gut/pulls"]
+	if created["head"] != "feat/login" || created["base"] != "main" || created["draft"] != true {
+		t.Errorf("pull request sent = %v", created)
+	}
+	reviewers := received["POST /repos/julien040/gut/pulls/42/requested_reviewers"]["reviewers"].([]interface{})
+	if len(reviewers) != 1 || reviewers[0] != "octocat" {
+		t.Errorf("reviewers sent = %v", reviewers)
+	}
+}
+
+func TestGitHub_CreatePullRequest_errors(t *testing.T) {
+	server, _ := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /repos/julien040/gut/pulls":                       respond(201, `{"number": 7, "html_url": "https://github.com/julien040/gut/pull/7"}`),
+		"POST /repos/julien040/gut/pulls/7/requested_reviewers": respond(422, `{"message": "Reviews may only be requested from collaborators"}`),
+	})
+
+	pr, err := (&GitHub{API: server.URL}).CreatePullRequest(Credentials{Password: "token"}, PullRequestOptions{Repo: "julien040/gut", Reviewers: []string{"stranger"}})
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("GitHub.CreatePullRequest() error = %v, want a PartialError", err)
+	}
+	if pr.Number != 7 {
+		t.Errorf("GitHub.CreatePullRequest() = %+v, want the pull request opened", pr)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 422 || apiErr.Message != "Reviews may only be requested from collaborators" {
+		t.Errorf("GitHub.CreatePullRequest() error = %v, want the message of the API", err)
+	}
+}
+
+func TestGitLab_CreatePullRequest(t *testing.T) {
+	server, received := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /users": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("username") != "alice" {
+				respond(200, `[]`)(w, r)
+				return
+			}
+			respond(200, `[{"id": 12}]`)(w, r)
+		},
+		"POST /projects/group%2Fsub%2Frepo/merge_requests": respond(201, `{"iid": 3, "web_url": "https://gitlab.com/group/sub/repo/-/merge_requests/3"}`),
+	})
+
+	pr, err := (&GitLab{API: server.URL}).CreatePullRequest(Credentials{Password: "token"}, PullRequestOptions{
+		Repo:      "group/sub/repo",
+		Head:      "feat",
+		Base:      "main",
+		Title:     "Add the login page",
+		Reviewers: []string{"alice"},
+		Labels:    []string{"frontend", "auth"},
+		Draft:     true,
+	})
+	if err != nil {
+		t.Fatalf("GitLab.CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 3 || pr.URL != "https://gitlab.com/group/sub/repo/-/merge_requests/3" {
+		t.Errorf("GitLab.CreatePullRequest() = %+v", pr)
+	}
+	created := received["POST /projects/group%2Fsub%2Frepo/merge_requests"]
+	if created["title"] != "Draft: Add the login page" || created["labels"] != "frontend,auth" || created["source_branch"] != "feat" {
+		t.Errorf("merge request sent = %v", created)
+	}
+	if ids := created["reviewer_ids"].([]interface{}); len(ids) != 1 || ids[0] != float64(12) {
+		t.Errorf("reviewers sent = %v", ids)
+	}
+
+	_, err = (&GitLab{API: server.URL}).CreatePullRequest(Credentials{Password: "token"}, PullRequestOptions{Repo: "group/sub/repo", Reviewers: []string{"bob"}})
+	if err == nil {
+		t.Errorf("GitLab.CreatePullRequest() with an unknown reviewer should fail")
+	}
+}
+
+func TestBitbucket_CreatePullRequest(t *testing.T) {
+	server, received := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /workspaces/team/members": respond(200, `{"values": [{"user": {"uuid": "{abc}", "nickname": "alice"}}]}`),
+		"POST /repositories/team/repo/pullrequests": func(w http.ResponseWriter, r *http.Request) {
+			if username, password, ok := r.BasicAuth(); !ok || username != "me" || password != "secret" {
+				t.Errorf("basic auth = %s %s, want the credentials of the profile", username, password)
+			}
+			respond(201, `{"id": 5, "links": {"html": {"href": "https://bitbucket.org/team/repo/pull-requests/5"}}}`)(w, r)
+		},
+	})
+
+	pr, err := (&Bitbucket{API: server.URL}).CreatePullRequest(Credentials{Username: "me", Password: "secret"}, PullRequestOptions{
+		Repo:      "team/repo",
+		Head:      "feat",
+		Base:      "main",
+		Title:     "Add the login page",
+		Reviewers: []string{"alice"},
+	})
+	if err != nil {
+		t.Fatalf("Bitbucket.CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 5 || pr.URL != "https://bitbucket.org/team/repo/pull-requests/5" {
+		t.Errorf("Bitbucket.CreatePullRequest() = %+v", pr)
+	}
+	reviewers := received["POST /repositories/team/repo/pullrequests"]["reviewers"].([]interface{})
+	if len(reviewers) != 1 || reviewers[0].(map[string]interface{})["uuid"] != "{abc}" {
+		t.Errorf("reviewers sent = %v", reviewers)
+	}
+
+	_, err = (&Bitbucket{API: server.URL}).CreatePullRequest(Credentials{Username: "me", Password: "secret"}, PullRequestOptions{Repo: "team/repo", Labels: []string{"bug"}})
+	if err == nil {
+		t.Errorf("Bitbucket.CreatePullRequest() with labels should fail")
+	}
+}
+
+func TestGitea_CreatePullRequest(t *testing.T) {
+	server, received := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /repos/owner/repo/labels": respond(200, `[{"id": 4, "name": "bug"}, {"id": 9, "name": "ui"}]`),
+		"POST /repos/owner/repo/pulls": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "token secret" {
+				t.Errorf("Authorization = %q, want the token", r.Header.Get("Authorization"))
+			}
+			respond(201, `{"number": 8, "html_url": "https://codeberg.org/owner/repo/pulls/8"}`)(w, r)
+		},
+		"POST /repos/owner/repo/pulls/8/requested_reviewers": respond(201, `[]`),
+	})
+
+	pr, err := (&Gitea{API: server.URL}).CreatePullRequest(Credentials{Password: "secret"}, PullRequestOptions{
+		Repo:      "owner/repo",
+		Head:      "fix",
+		Base:      "main",
+		Title:     "Fix the menu",
+		Reviewers: []string{"alice"},
+		Labels:    []string{"ui"},
+		Draft:     true,
+	})
+	if err != nil {
+		t.Fatalf("Gitea.CreatePullRequest() error = %v", err)
+	}
+	if pr.Number != 8 || pr.URL != "https://codeberg.org/owner/repo/pulls/8" {
+		t.Errorf("Gitea.CreatePullRequest() = %+v", pr)
+	}
+	created := received["POST /repos/owner/repo/pulls"]
+	if created["title"] != "WIP: Fix the menu" || created["head"] != "fix" {
+		t.Errorf("pull request sent = %v", created)
+	}
+	if labels := created["labels"].([]interface{}); len(labels) != 1 || labels[0] != float64(9) {
+		t.Errorf("labels sent = %v", labels)
+	}
+
+	_, err = (&Gitea{API: server.URL}).CreatePullRequest(Credentials{Password: "secret"}, PullRequestOptions{Repo: "owner/repo", Labels: []string{"unknown"}})
+	if err == nil {
+		t.Errorf("Gitea.CreatePullRequest() with an unknown label should fail")
+	}
+}
+
+func TestGetRepository(t *testing.T) {
+	server, _ := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /repos/julien040/gut":       respond(200, `{"full_name": "julien040/gut", "default_branch": "main", "private": false, "html_url": "https://github.com/julien040/gut"}`),
+		"GET /projects/group%2Frepo":     respond(200, `{"path_with_namespace": "group/repo", "default_branch": "develop", "visibility": "internal"}`),
+		"GET /repositories/team/project": respond(200, `{"full_name": "team/project", "is_private": true, "mainbranch": {"name": "master"}}`),
+	})
+
+	tests := []struct {
+		provider Provider
+		repo     string
+		want     Repository
+	}{
+		{&GitHub{API: server.URL}, "julien040/gut", Repository{FullName: "julien040/gut", DefaultBranch: "main", URL: "https://github.com/julien040/gut"}},
+		{&Gitea{API: server.URL}, "julien040/gut", Repository{FullName: "julien040/gut", DefaultBranch: "main", URL: "https://github.com/julien040/gut"}},
+		{&GitLab{API: server.URL}, "group/repo", Repository{FullName: "group/repo", DefaultBranch: "develop", Private: true}},
+		{&Bitbucket{API: server.URL}, "team/project", Repository{FullName: "team/project", DefaultBranch: "master", Private: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider.Name(), func(t *testing.T) {
+			got, err := tt.provider.GetRepository(Credentials{}, tt.repo)
+			if err != nil {
+				t.Fatalf("GetRepository() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetRepository() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		kind    string
+		host    string
+		apiURL  string
+		compare string
+		wantAPI string
+		wantErr bool
+	}{
+		{"github", "github.com", "", "https://github.com/a/b/compare/main...feat?quick_pull=1", "https://api.github.com", false},
+		{"github", "github.corp.com", "", "https://github.corp.com/a/b/compare/main...feat?quick_pull=1", "https://github.corp.com/api/v3", false},
+		{"gitlab", "git.company.com", "https://git.company.com/gitlab/api/v4/", "https://git.company.com/a/b/-/merge_requests/new?merge_request%5Bsource_branch%5D=feat&merge_request%5Btarget_branch%5D=main", "https://git.company.com/gitlab/api/v4", false},
+		{"bitbucket", "bitbucket.org", "", "https://bitbucket.org/a/b/pull-requests/new?source=feat&dest=main", "https://api.bitbucket.org/2.0", false},
+		{"Forgejo", "codeberg.org", "", "https://codeberg.org/a/b/compare/main...feat", "https://codeberg.org/api/v1", false},
+		{"sourcehut", "git.sr.ht", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind+" "+tt.host, func(t *testing.T) {
+			got, err := New(tt.kind, tt.host, tt.apiURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if compare := got.CompareURL("a/b", "main", "feat"); compare != tt.compare {
+				t.Errorf("CompareURL() = %v, want %v", compare, tt.compare)
+			}
+			var api string
+			switch p := got.(type) {
+			case *GitHub:
+				api = p.API
+			case *GitLab:
+				api = p.API
+			case *Bitbucket:
+				api = p.API
+			case *Gitea:
+				api = p.API
+			}
+			if api != tt.wantAPI {
+				t.Errorf("New() API = %v, want %v", api, tt.wantAPI)
+			}
+		})
+	}
+}
+
+func Test_reviewDecision(t *testing.T) {
+	tests := []struct {
+		name         string
+		reviews      []review
+		want         string
+		wantApproved int
+	}{
+		{"no review", nil, ReviewPending, 0},
+		{"only comments", []review{{"alice", ""}}, ReviewPending, 0},
+		{"approved", []review{{"alice", "approved"}, {"bob", ""}}, ReviewApproved, 1},
+		{"changes then approved", []review{{"alice", "changes"}, {"alice", "approved"}}, ReviewApproved, 1},
+		{"approved then changes", []review{{"alice", "approved"}, {"bob", "changes"}}, ReviewChangesRequested, 1},
+		{"dismissed", []review{{"alice", "approved"}, {"alice", "dismissed"}}, ReviewPending, 0},
+		{"comment after approval", []review{{"alice", "approved"}, {"alice", ""}}, ReviewApproved, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, approvedBy := reviewDecision(tt.reviews)
+			if got != tt.want || len(approvedBy) != tt.wantApproved {
+				t.Errorf("reviewDecision() = %v %v, want %v with %d approvals", got, approvedBy, tt.want, tt.wantApproved)
+			}
+		})
+	}
+}
+
+func TestGetPullRequest(t *testing.T) {
+	server, _ := newMockServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /repos/a/b/pulls/3":                         respond(200, `{"number": 3, "title": "Fix", "user": {"login": "bob"}, "head": {"ref": "fix", "repo": {"full_name": "bob/b"}}, "base": {"ref": "main", "repo": {"full_name": "a/b"}}, "mergeable": true, "mergeable_state": "behind"}`),
+		"GET /repos/a/b/pulls/3/reviews":                 respond(200, `[{"user": {"login": "alice"}, "state": "CHANGES_REQUESTED"}, {"user": {"login": "alice"}, "state": "APPROVED"}]`),
+		"GET /projects/a%2Fb/merge_requests/4":           respond(200, `{"iid": 4, "title": "Feat", "author": {"username": "carol"}, "source_branch": "feat", "target_branch": "main", "source_project_id": 1, "target_project_id": 1, "detailed_merge_status": "conflict"}`),
+		"GET /projects/a%2Fb/merge_requests/4/approvals": respond(200, `{"approvals_left": 1, "approved_by": [{"user": {"username": "dave"}}]}`),
+		"GET /repositories/a/b/pullrequests/5":           respond(200, `{"id": 5, "source": {"branch": {"name": "x"}, "repository": {"full_name": "a/b"}}, "destination": {"branch": {"name": "main"}, "repository": {"full_name": "a/b"}}, "participants": [{"user": {"nickname": "erin"}, "state": "changes_requested"}]}`),
+	})
+
+	tests := []struct {
+		provider Provider
+		number   int
+		want     PullRequest
+	}{
+		{&GitHub{API: server.URL}, 3, PullRequest{Number: 3, Title: "Fix", Author: "bob", Head: "fix", Base: "main", Fork: true, Review: ReviewApproved, ApprovedBy: []string{"alice"}, Mergeable: MergeBehind}},
+		{&GitLab{API: server.URL}, 4, PullRequest{Number: 4, Title: "Feat", Author: "carol", Head: "feat", Base: "main", Review: ReviewPending, ApprovedBy: []string{"dave"}, Mergeable: MergeConflicts}},
+		{&Bitbucket{API: server.URL}, 5, PullRequest{Number: 5, Head: "x", Base: "main", Review: ReviewChangesRequested}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider.Name(), func(t *testing.T) {
+			got, err := tt.provider.GetPullRequest(Credentials{}, "a/b", tt.number)
+			if err != nil {
+				t.Fatalf("GetPullRequest() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPullRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}